@@ -1,29 +1,187 @@
 package main
 
 import (
+	"errors"
 	"log"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"time"
 
 	"github.com/philmacfly/wheretoeat/pkg/config"
+	"github.com/philmacfly/wheretoeat/pkg/selection"
+	"github.com/philmacfly/wheretoeat/pkg/service"
 	"github.com/philmacfly/wheretoeat/pkg/venue"
 	"github.com/philmacfly/wheretoeat/pkg/web"
 )
 
+func openVenueStore(s config.Storage) (venue.VenueStore, error) {
+	datafolder := s.DataFolder
+	if datafolder == "" {
+		datafolder = "data"
+	}
+	switch s.Backend {
+	case "", "json":
+		return venue.NewJSONStore(datafolder), nil
+	case "sqlite":
+		sqlitefile := s.SQLiteFile
+		if sqlitefile == "" {
+			sqlitefile = "wheretoeat.db"
+		}
+		st, err := venue.NewSQLiteStore(sqlitefile)
+		if err != nil {
+			return nil, err
+		}
+		imported, err := st.JSONImported()
+		if err != nil {
+			return nil, err
+		}
+		if !imported {
+			n, err := venue.ImportJSONFolder(datafolder, st)
+			if err != nil {
+				return nil, errors.New("Error importing JSON data folder: " + err.Error())
+			}
+			err = st.MarkJSONImported(datafolder)
+			if err != nil {
+				return nil, err
+			}
+			if n > 0 {
+				log.Println("Imported", n, "venues from", datafolder, "into", sqlitefile)
+			}
+		}
+		return st, nil
+	}
+	return nil, errors.New("Unknown storage backend: " + s.Backend)
+}
+
+func openPlaceProvider(c config.Config) (venue.PlaceProvider, error) {
+	switch c.Places.Provider {
+	case "", venue.GoogleProviderName:
+		return venue.NewGoogleProvider(c.GoogleAPIKey)
+	case venue.OSMProviderName:
+		return venue.NewOSMProvider(c.Places.NominatimURL, c.Places.UserAgent)
+	case venue.FixtureProviderName:
+		return venue.NewFixtureProvider(c.Places.FixtureFolder)
+	}
+	return nil, errors.New("Unknown place provider: " + c.Places.Provider)
+}
+
+//cachePlaceProvider wraps the provider with a cache in the data folder, without a TTL only the requests are counted
+func cachePlaceProvider(p venue.PlaceProvider, c config.Config) (venue.PlaceProvider, error) {
+	var ttl time.Duration
+	var err error
+	if c.Places.Cache.TTL != "" {
+		ttl, err = time.ParseDuration(c.Places.Cache.TTL)
+		if err != nil {
+			return nil, errors.New("Error parsing cache TTL: " + err.Error())
+		}
+	}
+	var cost venue.PlaceCost
+	if p.Name() == venue.GoogleProviderName {
+		cost = venue.GoogleCost
+	}
+	if c.Places.Cache.SearchCost > 0 {
+		cost.Search = c.Places.Cache.SearchCost
+	}
+	if c.Places.Cache.DetailsCost > 0 {
+		cost.Details = c.Places.Cache.DetailsCost
+	}
+	datafolder := c.Storage.DataFolder
+	if datafolder == "" {
+		datafolder = "data"
+	}
+	return venue.NewCachedProvider(p, filepath.Join(datafolder, "cache", "places"), ttl, cost), nil
+}
+
+//loadHolidays reads the public holidays from the iCalendar file
+func loadHolidays(filename string) ([]venue.Holiday, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.New("Error opening file: " + err.Error())
+	}
+	defer file.Close()
+	return venue.ParseICSHolidays(file)
+}
+
 func main() {
 	rand.Seed(time.Now().Unix())
 	c, err := config.LoadConfig("config.json")
 	if err != nil {
 		log.Fatal("Error loading config:", err)
 	}
-	err = venue.SetupPlaceAPI(c.GoogleAPIKey)
+	provider, err := openPlaceProvider(c)
+	if err != nil {
+		log.Fatal("Error setting up place provider:", err)
+	}
+	provider, err = cachePlaceProvider(provider, c)
+	if err != nil {
+		log.Fatal("Error setting up place cache:", err)
+	}
+	venue.SetPlaceProvider(provider)
+	ratelimit := c.Places.RateLimit
+	//the usage policy of the public Nominatim instance allows one request per second
+	if ratelimit == 0 && provider.Name() == venue.OSMProviderName {
+		ratelimit = 1
+	}
+	service.SetRefreshLimits(c.Places.Concurrency, ratelimit)
+	store, err := openVenueStore(c.Storage)
+	if err != nil {
+		log.Fatal("Error opening venue storage:", err)
+	}
+	n, err := venue.MigrateLegacyIDs(store)
+	if err != nil {
+		log.Fatal("Error migrating legacy venue IDs:", err)
+	}
+	if n > 0 {
+		log.Println("Gave", n, "venues a new stable ID")
+	}
+	n, err = venue.MigrateVisitIDs(store)
+	if err != nil {
+		log.Fatal("Error migrating visit IDs:", err)
+	}
+	if n > 0 {
+		log.Println("Gave the visits of", n, "venues an ID")
+	}
+	service.SetVenueStore(store)
+	params := c.Selection.Parameters
+	if params == nil {
+		params = make(map[string]map[string]float64)
+	}
+	//the weights used to be configured on their own
+	w := c.Weight
+	if _, ok := params[selection.WeightedName]; !ok && (w.Rating != 0 || w.LastVisit != 0 || w.DayCount != 0) {
+		params[selection.WeightedName] = map[string]float64{"rating": w.Rating, "lastvisit": w.LastVisit, "daycount": w.DayCount}
+	}
+	err = service.SetSelection(c.Selection.Strategy, params)
+	if err != nil {
+		log.Fatal("Error setting up selection:", err)
+	}
+	if c.Timezone != "" {
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			log.Fatal("Error loading timezone:", err)
+		}
+		service.SetLocation(loc)
+	}
+	if c.Holidays != "" {
+		hh, err := loadHolidays(c.Holidays)
+		if err != nil {
+			log.Fatal("Error loading holidays:", err)
+		}
+		venue.SetHolidays(hh)
+		log.Println("Loaded", len(hh), "holidays from", c.Holidays)
+	}
+	schedule, err := service.NewSchedule(c.Refresh.Interval, c.Refresh.QuietStart, c.Refresh.QuietEnd)
+	if err != nil {
+		log.Fatal("Error reading refresh schedule:", err)
+	}
+	service.StartScheduler(schedule)
+	err = web.SetupAssets(c.ThemeFolder)
 	if err != nil {
-		log.Fatal("Error setting up Places API:", err)
+		log.Fatal("Error setting up templates:", err)
 	}
-	venue.SetDataFolder("data")
-	web.SetWeights(c.Weight)
 	r := web.SetupRouters("/")
 	log.Fatal(http.ListenAndServe(c.Host+":"+strconv.Itoa(c.Port), r))
 }
@@ -8,10 +8,62 @@ import (
 
 //Config is the struct to save and load the config file
 type Config struct {
-	GoogleAPIKey string `json:"googleapikey"`
-	Host         string `json:"host"`
-	Port         int    `json:"port"`
-	Weight       Weight `json:"weight"`
+	GoogleAPIKey string  `json:"googleapikey"`
+	Host         string  `json:"host"`
+	Port         int     `json:"port"`
+	Weight       Weight  `json:"weight"`
+	Storage      Storage `json:"storage"`
+	ThemeFolder  string  `json:"themefolder"`
+	Places       Places  `json:"places"`
+	Refresh      Refresh `json:"refresh"`
+	//Timezone is the IANA name of the timezone of the opening hours, like Europe/Berlin
+	Timezone string `json:"timezone"`
+	//Holidays is the path of an iCalendar file listing the public holidays
+	Holidays  string    `json:"holidays"`
+	Selection Selection `json:"selection"`
+}
+
+//Selection is the struct to save the strategy picking the next venue if none is asked for and the parameters of
+//the strategies by their name, like {"weighted": {"rating": 2}}
+type Selection struct {
+	Strategy   string                        `json:"strategy"`
+	Parameters map[string]map[string]float64 `json:"parameters"`
+}
+
+//Refresh is the struct to save how often venues are refreshed in the background, like "24h", and the quiet hours,
+//like "22:00" to "07:00", in which no refresh is started
+type Refresh struct {
+	Interval   string `json:"interval"`
+	QuietStart string `json:"quietstart"`
+	QuietEnd   string `json:"quietend"`
+}
+
+//Places is the struct to save where venues are looked up. Provider is google (default), osm or fixture
+type Places struct {
+	Provider      string `json:"provider"`
+	NominatimURL  string `json:"nominatimurl"`
+	UserAgent     string `json:"useragent"`
+	FixtureFolder string `json:"fixturefolder"`
+	//Concurrency is the number of venues refreshed at the same time
+	Concurrency int `json:"concurrency"`
+	//RateLimit is the number of requests per second sent to the provider
+	RateLimit float64     `json:"ratelimit"`
+	Cache     PlacesCache `json:"cache"`
+}
+
+//PlacesCache is the struct to save how long responses of the provider are cached, like "24h", and the estimated
+//price of a request, which defaults to the list price for Google
+type PlacesCache struct {
+	TTL         string  `json:"ttl"`
+	SearchCost  float64 `json:"searchcost"`
+	DetailsCost float64 `json:"detailscost"`
+}
+
+//Storage is the struct to save where and how the venues are persisted
+type Storage struct {
+	Backend    string `json:"backend"`
+	DataFolder string `json:"datafolder"`
+	SQLiteFile string `json:"sqlitefile"`
 }
 
 //Weight is the struct to save the weights of the criteria
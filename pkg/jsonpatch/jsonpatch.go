@@ -0,0 +1,297 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func decode(data []byte) (interface{}, error) {
+	var res interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//MergePatch applies a JSON Merge Patch as described in RFC 7396 to the document
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, errors.New("Error decoding document: " + err.Error())
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, errors.New("Error decoding merge patch: " + err.Error())
+	}
+	return json.Marshal(mergeValue(d, p))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergeValue(t[k], v)
+	}
+	return t
+}
+
+//Operation is a single operation of a JSON Patch as described in RFC 6902
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+//Apply applies a JSON Patch as described in RFC 6902 to the document. Either all operations succeed or an error is returned
+func Apply(doc []byte, patch []byte) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, errors.New("Error decoding document: " + err.Error())
+	}
+	var ops []Operation
+	err = json.Unmarshal(patch, &ops)
+	if err != nil {
+		return nil, errors.New("Error decoding json patch: " + err.Error())
+	}
+	for i, op := range ops {
+		d, err = applyOperation(d, op)
+		if err != nil {
+			return nil, errors.New("Operation " + strconv.Itoa(i) + " (" + op.Op + " " + op.Path + "): " + err.Error())
+		}
+	}
+	return json.Marshal(d)
+}
+
+func applyOperation(doc interface{}, op Operation) (interface{}, error) {
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, errors.New("Missing value")
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, errors.New("Error decoding value: " + err.Error())
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, op.Path, value)
+		case "replace":
+			doc, _, err = remove(doc, op.Path)
+			if err != nil {
+				return nil, err
+			}
+			return add(doc, op.Path, value)
+		}
+		current, err := get(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, errors.New("Test failed")
+		}
+		return doc, nil
+	case "remove":
+		doc, _, err := remove(doc, op.Path)
+		return doc, err
+	case "move":
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, errors.New("Can't move a value into one of its children")
+		}
+		doc, value, err := remove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, value)
+	case "copy":
+		value, err := get(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, op.Path, deepCopy(value))
+	}
+	return nil, errors.New("Unknown operation")
+}
+
+//parsePointer splits a JSON Pointer as described in RFC 6901 into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("Pointer has to start with /")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		t = strings.Replace(t, "~1", "/", -1)
+		tokens[i] = strings.Replace(t, "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, errors.New("Invalid array index " + token)
+	}
+	if i > length || (!allowEnd && i == length) {
+		return 0, errors.New("Array index " + token + " out of bounds")
+	}
+	return i, nil
+}
+
+func get(doc interface{}, pointer string) (interface{}, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, t := range tokens {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			v, ok := c[t]
+			if !ok {
+				return nil, errors.New("Path " + pointer + " does not exist")
+			}
+			current = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			current = c[i]
+		default:
+			return nil, errors.New("Path " + pointer + " does not exist")
+		}
+	}
+	return current, nil
+}
+
+//splitParent gives back the value holding the target of the pointer and the last reference token
+func splitParent(doc interface{}, pointer string) (interface{}, string, error) {
+	i := strings.LastIndex(pointer, "/")
+	if i < 0 {
+		return nil, "", errors.New("Pointer has to start with /")
+	}
+	parent, err := get(doc, pointer[:i])
+	if err != nil {
+		return nil, "", err
+	}
+	tokens, err := parsePointer(pointer[i:])
+	if err != nil {
+		return nil, "", err
+	}
+	return parent, tokens[0], nil
+}
+
+//set replaces the value the pointer points to, which is needed for arrays as they change their length
+func set(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	parent, token, err := splitParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = value
+	case []interface{}:
+		i, err := arrayIndex(token, len(p), false)
+		if err != nil {
+			return nil, err
+		}
+		p[i] = value
+	}
+	return doc, nil
+}
+
+func add(doc interface{}, pointer string, value interface{}) (interface{}, error) {
+	if pointer == "" {
+		return value, nil
+	}
+	parent, token, err := splitParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[token] = value
+		return doc, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(p), true)
+		if err != nil {
+			return nil, err
+		}
+		arr := append(p[:i:i], append([]interface{}{value}, p[i:]...)...)
+		return set(doc, pointer[:strings.LastIndex(pointer, "/")], arr)
+	}
+	return nil, errors.New("Parent of " + pointer + " is neither an object nor an array")
+}
+
+func remove(doc interface{}, pointer string) (interface{}, interface{}, error) {
+	if pointer == "" {
+		return nil, doc, nil
+	}
+	parent, token, err := splitParent(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		v, ok := p[token]
+		if !ok {
+			return nil, nil, errors.New("Path " + pointer + " does not exist")
+		}
+		delete(p, token)
+		return doc, v, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(p), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := p[i]
+		arr := append(p[:i:i], p[i+1:]...)
+		doc, err = set(doc, pointer[:strings.LastIndex(pointer, "/")], arr)
+		return doc, v, err
+	}
+	return nil, nil, errors.New("Parent of " + pointer + " is neither an object nor an array")
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, e := range c {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = deepCopy(e)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,44 @@
+package selection
+
+import (
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//LeastRecentName is the strategy picking the venue that was not visited for the longest time, venues never visited
+//come first. If several are tied one of them is picked at random
+const LeastRecentName = "leastrecent"
+
+func init() {
+	Register(LeastRecentName, func(p Parameters) (Strategy, error) {
+		err := p.check()
+		if err != nil {
+			return nil, err
+		}
+		return leastRecent{}, nil
+	})
+}
+
+type leastRecent struct{}
+
+func (leastRecent) Pick(candidates []venue.Venue, now time.Time) (Result, error) {
+	lastvisits := make([]time.Time, len(candidates))
+	var oldest time.Time
+	for i, v := range candidates {
+		if len(v.Visits) > 0 {
+			lastvisits[i] = v.LastVisit()
+		}
+		if i == 0 || lastvisits[i].Before(oldest) {
+			oldest = lastvisits[i]
+		}
+	}
+	//every venue tied for the oldest visit gets the same chance, the others none
+	scores := make([]float64, len(candidates))
+	for i := range candidates {
+		if lastvisits[i].Equal(oldest) {
+			scores[i] = 1
+		}
+	}
+	return pickByScore(candidates, scores)
+}
@@ -0,0 +1,64 @@
+package selection
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//RoundRobinName is the strategy going through the candidates by name, one after another. Where it is in the list
+//is only kept in memory, after a restart it begins again with the first venue
+const RoundRobinName = "roundrobin"
+
+func init() {
+	Register(RoundRobinName, func(p Parameters) (Strategy, error) {
+		err := p.check()
+		if err != nil {
+			return nil, err
+		}
+		return &roundRobin{}, nil
+	})
+}
+
+type roundRobin struct {
+	mutex sync.Mutex
+	last  string
+}
+
+func (r *roundRobin) Pick(candidates []venue.Venue, now time.Time) (Result, error) {
+	if len(candidates) < 1 {
+		return Result{}, ErrNoCandidates
+	}
+	sorted := make([]venue.Venue, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		return roundRobinKey(sorted[i]) < roundRobinKey(sorted[j])
+	})
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	//the venue following the last one by name, so venues added or left out in between don't disturb the order
+	next := sorted[0]
+	for _, v := range sorted {
+		if roundRobinKey(v) > r.last {
+			next = v
+			break
+		}
+	}
+	r.last = roundRobinKey(next)
+	//the order leaves no choice, the next venue is certain
+	scores := make([]float64, len(candidates))
+	for i, v := range candidates {
+		if v.VenueID == next.VenueID {
+			scores[i] = 1
+		}
+	}
+	return pickByScore(candidates, scores)
+}
+
+//roundRobinKey orders the venues by name, venues with the same name by their ID
+func roundRobinKey(v venue.Venue) string {
+	return strings.ToLower(v.Name) + "\x00" + v.VenueID
+}
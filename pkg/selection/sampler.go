@@ -0,0 +1,71 @@
+package selection
+
+import (
+	"math"
+	"math/rand"
+)
+
+//SampleWeighted picks an index with a chance proportional to its score. The scores are summed up to cumulative
+//weights and a random point below the total is searched in them, so no candidate is copied and fractions are kept.
+//Negative and NaN scores count as zero. A score of +Inf is certain: if there are any, one of them is picked with
+//the same chance and all others have none. If no score is above zero every index has the same chance, only
+//without scores ErrNoCandidates is returned. Next to the index the probability of every score is given back
+func SampleWeighted(scores []float64) (int, []float64, error) {
+	if len(scores) == 0 {
+		return -1, nil, ErrNoCandidates
+	}
+	weights := sampleWeights(scores)
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		total += w
+		cumulative[i] = total
+	}
+	probabilities := make([]float64, len(scores))
+	previous := 0.0
+	for i, c := range cumulative {
+		probabilities[i] = (c - previous) / total
+		previous = c
+	}
+	point := rand.Float64() * total
+	//the first cumulative weight above the point, scores of zero can't be hit as their weight equals the previous one
+	lo, hi := 0, len(cumulative)-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if cumulative[mid] > point {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return lo, probabilities, nil
+}
+
+//sampleWeights gives back the weights SampleWeighted draws with, see there for the rules
+func sampleWeights(scores []float64) []float64 {
+	weights := make([]float64, len(scores))
+	infinite := false
+	for i, s := range scores {
+		if math.IsInf(s, 1) {
+			weights[i] = 1
+			infinite = true
+		}
+	}
+	if infinite {
+		return weights
+	}
+	total := 0.0
+	for i, s := range scores {
+		if s > 0 {
+			weights[i] = s
+			total += s
+		}
+	}
+	if total > 0 {
+		return weights
+	}
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
@@ -0,0 +1,64 @@
+package selection
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleWeighted(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{"single", []float64{3}, []float64{1}},
+		{"equal", []float64{1, 1, 1, 1}, []float64{0.25, 0.25, 0.25, 0.25}},
+		{"fractions", []float64{0.5, 1.5}, []float64{0.25, 0.75}},
+		{"zero in between", []float64{1, 0, 3}, []float64{0.25, 0, 0.75}},
+		{"zero at the ends", []float64{0, 2, 0}, []float64{0, 1, 0}},
+		{"invalid scores count as zero", []float64{math.NaN(), -1, math.Inf(-1), 4}, []float64{0, 0, 0, 1}},
+		{"infinite score is certain", []float64{2, math.Inf(1), 4}, []float64{0, 1, 0}},
+		{"infinite scores share", []float64{math.Inf(1), 1, math.Inf(1)}, []float64{0.5, 0, 0.5}},
+		{"no score above zero", []float64{0, 0}, []float64{0.5, 0.5}},
+		{"only invalid scores", []float64{-1, math.NaN(), 0}, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts := make([]int, len(tt.scores))
+			const draws = 20000
+			for i := 0; i < draws; i++ {
+				index, probabilities, err := SampleWeighted(tt.scores)
+				if err != nil {
+					t.Fatalf("SampleWeighted(%v) failed: %v", tt.scores, err)
+				}
+				if index < 0 || index >= len(tt.scores) {
+					t.Fatalf("SampleWeighted(%v) gave back index %d", tt.scores, index)
+				}
+				counts[index]++
+				if i > 0 {
+					continue
+				}
+				for j := range tt.want {
+					if math.Abs(probabilities[j]-tt.want[j]) > 1e-9 {
+						t.Errorf("SampleWeighted(%v) probabilities = %v, want %v", tt.scores, probabilities, tt.want)
+						break
+					}
+				}
+			}
+			for j, want := range tt.want {
+				got := float64(counts[j]) / draws
+				if want == 0 && counts[j] > 0 || math.Abs(got-want) > 0.02 {
+					t.Errorf("index %d was drawn %.3f of the time, want %.3f", j, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSampleWeightedWithoutScores(t *testing.T) {
+	for _, scores := range [][]float64{nil, {}} {
+		if index, _, err := SampleWeighted(scores); err != ErrNoCandidates {
+			t.Errorf("SampleWeighted(%v) = %d, %v, want ErrNoCandidates", scores, index, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package selection
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//ErrNoCandidates is returned if there is no venue to choose from
+var ErrNoCandidates = errors.New("No candidates to choose from")
+
+//Strategy chooses the next venue to visit out of the candidates
+type Strategy interface {
+	Pick(candidates []venue.Venue, now time.Time) (Result, error)
+}
+
+//Result is the venue chosen by a strategy together with the chance every candidate had
+type Result struct {
+	Venue      venue.Venue
+	Candidates []Candidate
+}
+
+//Candidate is a venue that could have been picked, Score is what the strategy based its Probability on
+type Candidate struct {
+	VenueID     string
+	Name        string
+	Score       float64
+	Probability float64
+}
+
+//pickByScore picks one of the candidates with a chance proportional to its score, see SampleWeighted
+func pickByScore(candidates []venue.Venue, scores []float64) (Result, error) {
+	i, probabilities, err := SampleWeighted(scores)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Venue: candidates[i]}
+	for j, v := range candidates {
+		score := scores[j]
+		//JSON has no room for scores which are not a number
+		if math.IsNaN(score) || math.IsInf(score, 0) {
+			score = 0
+		}
+		res.Candidates = append(res.Candidates, Candidate{VenueID: v.VenueID, Name: v.Name, Score: score,
+			Probability: probabilities[j]})
+	}
+	return res, nil
+}
+
+//Parameters are the settings of a strategy from the config, like the weights of the weighted strategy
+type Parameters map[string]float64
+
+//get gives back the parameter or def if it is not set
+func (p Parameters) get(name string, def float64) float64 {
+	if value, ok := p[name]; ok {
+		return value
+	}
+	return def
+}
+
+//check gives back an error for the first parameter that is not one of known, so a typo in the config is noticed
+func (p Parameters) check(known ...string) error {
+	var names []string
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for _, k := range known {
+			if name == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("Unknown parameter " + name)
+		}
+	}
+	return nil
+}
+
+//Factory builds a strategy with its parameters, missing parameters get their defaults
+type Factory func(p Parameters) (Strategy, error)
+
+var factories = make(map[string]Factory)
+
+//Register makes a strategy available under the name, the strategies of this package register themselves
+func Register(name string, f Factory) {
+	factories[name] = f
+}
+
+//Names gives back the names of all registered strategies sorted
+func Names() []string {
+	var res []string
+	for name := range factories {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+//New builds the strategy registered under the name
+func New(name string, p Parameters) (Strategy, error) {
+	f, ok := factories[name]
+	if !ok {
+		return nil, errors.New("Unknown strategy " + name)
+	}
+	s, err := f(p)
+	if err != nil {
+		return nil, errors.New("Error setting up strategy " + name + ": " + err.Error())
+	}
+	return s, nil
+}
+
+//daysSinceLastVisit gives back the full days since the last visit of the venue, or never if it was not visited yet
+func daysSinceLastVisit(v venue.Venue, now time.Time, never int) int {
+	if len(v.Visits) == 0 {
+		return never
+	}
+	days := int(now.Sub(v.LastVisit()) / (24 * time.Hour))
+	if days < 0 {
+		return 1
+	}
+	return days
+}
@@ -0,0 +1,56 @@
+package selection
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+func TestNewChecksParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params Parameters
+		ok     bool
+	}{
+		{WeightedName, nil, true},
+		{WeightedName, Parameters{"rating": 2, "lastvisit": 0.5, "daycount": 0}, true},
+		{WeightedName, Parameters{"ratign": 2}, false},
+		{UniformName, nil, true},
+		{UniformName, Parameters{"rating": 1}, false},
+		{RoundRobinName, Parameters{"start": 1}, false},
+		{LeastRecentName, Parameters{}, true},
+		{LeastRecentName, Parameters{"lastvisit": 1}, false},
+		{"random", nil, false},
+	}
+	for _, tt := range tests {
+		_, err := New(tt.name, tt.params)
+		if tt.ok && err != nil {
+			t.Errorf("New(%s, %v) failed: %v", tt.name, tt.params, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("New(%s, %v) succeeded, want an error", tt.name, tt.params)
+		}
+	}
+}
+
+func TestWeightedPicksUnratedVenues(t *testing.T) {
+	strategy, err := New(WeightedName, nil)
+	if err != nil {
+		t.Fatalf("New(%s) failed: %v", WeightedName, err)
+	}
+	candidates := []venue.Venue{{VenueID: "a"}, {VenueID: "b"}}
+	res, err := strategy.Pick(candidates, time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Pick on unrated venues failed: %v", err)
+	}
+	if res.Venue.VenueID != "a" && res.Venue.VenueID != "b" {
+		t.Errorf("Pick gave back %q, want one of the candidates", res.Venue.VenueID)
+	}
+	for _, c := range res.Candidates {
+		if c.Score != 0 || math.Abs(c.Probability-0.5) > 1e-9 {
+			t.Errorf("candidate %s has score %v and probability %v, want 0 and 0.5", c.VenueID, c.Score, c.Probability)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package selection
+
+import (
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//UniformName is the strategy giving every candidate the same chance
+const UniformName = "uniform"
+
+func init() {
+	Register(UniformName, func(p Parameters) (Strategy, error) {
+		err := p.check()
+		if err != nil {
+			return nil, err
+		}
+		return uniform{}, nil
+	})
+}
+
+type uniform struct{}
+
+func (uniform) Pick(candidates []venue.Venue, now time.Time) (Result, error) {
+	scores := make([]float64, len(candidates))
+	for i := range scores {
+		scores[i] = 1
+	}
+	return pickByScore(candidates, scores)
+}
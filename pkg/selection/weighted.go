@@ -0,0 +1,50 @@
+package selection
+
+import (
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//WeightedName is the strategy preferring good venues that were not visited for a long time.
+//Its parameters are the weights rating, lastvisit and daycount
+const WeightedName = "weighted"
+
+func init() {
+	Register(WeightedName, func(p Parameters) (Strategy, error) {
+		err := p.check("rating", "lastvisit", "daycount")
+		if err != nil {
+			return nil, err
+		}
+		return weighted{rating: p.get("rating", 1), lastvisit: p.get("lastvisit", 1), daycount: p.get("daycount", 1)}, nil
+	})
+}
+
+type weighted struct {
+	rating    float64
+	lastvisit float64
+	daycount  float64
+}
+
+func (w weighted) Pick(candidates []venue.Venue, now time.Time) (Result, error) {
+	scores := make([]float64, len(candidates))
+	for i, v := range candidates {
+		scores[i] = w.score(v, now)
+	}
+	return pickByScore(candidates, scores)
+}
+
+//score grows with the rating and the days since the last visit and shrinks with the number of visits.
+//Venues never visited count as visited once a year ago. A daycount weight of zero leaves the visits out
+func (w weighted) score(v venue.Venue, now time.Time) float64 {
+	lastvisit := float64(daysSinceLastVisit(v, now, 356)) * w.lastvisit
+	daycount := 1.0
+	if len(v.Visits) > 0 {
+		daycount = float64(len(v.Visits))
+	}
+	daycount *= w.daycount
+	if daycount == 0 {
+		daycount = 1
+	}
+	return lastvisit / daycount * float64(v.Rating) * w.rating
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//setupFixtures points the service to an empty JSON store and the place fixtures of the repository
+func setupFixtures(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := venue.NewFixtureProvider("../../testdata/places")
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	venue.SetPlaceProvider(p)
+	SetVenueStore(venue.NewJSONStore(dir))
+	return func() {
+		venue.SetPlaceProvider(nil)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCreateVenueFromPlace(t *testing.T) {
+	defer setupFixtures(t)()
+
+	cc, err := SearchPlaces("Pizza")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cc) != 2 || cc[0].PlaceID != "pizza-roma" {
+		t.Fatalf("SearchPlaces() = %+v, want pizza-roma first of two candidates", cc)
+	}
+	v, err := VenueFromPlace(cc[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := CreateVenue(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := GetVenue(created.VenueID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Name != "Pizzeria Roma" || saved.Rating != 4 || saved.PlaceProvider != venue.FixtureProviderName ||
+		saved.PlaceID != "pizza-roma" {
+		t.Errorf("saved venue = %+v, want Pizzeria Roma with rating 4 from the fixtures", saved)
+	}
+	if saved.PhoneNumber != "+49 89 1234567" || len(saved.OpeningHours.Periods) != 4 ||
+		saved.BusinessStatus != venue.BusinessOperational {
+		t.Errorf("saved venue = %+v, want the details of pizza-roma", saved)
+	}
+
+	_, err = VenueFromPlace(venue.PlaceCandidate{Name: "No ID"})
+	if KindOf(err) != Invalid {
+		t.Errorf("VenueFromPlace without PlaceID = %v, want an Invalid error", err)
+	}
+}
+
+func TestUpdateFromPlaces(t *testing.T) {
+	defer setupFixtures(t)()
+
+	for _, v := range []venue.Venue{
+		{VenueID: "roma", Name: "Pizzeria Roma", PlaceProvider: venue.FixtureProviderName, PlaceID: "pizza-roma",
+			PhoneNumber: "+49 89 1111111"},
+		{VenueID: "napoli", Name: "Pizzeria Napoli", PlaceProvider: venue.FixtureProviderName, PlaceID: "pizza-napoli",
+			BusinessStatus: venue.BusinessOperational},
+		{VenueID: "gone", Name: "Gone", PlaceProvider: venue.FixtureProviderName, PlaceID: "pizza-gone"},
+		{VenueID: "manual", Name: "Without PlaceID"},
+	} {
+		err := store.Put(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := UpdateFromPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 2 || report.Unchanged != 0 || report.Failed != 1 || len(report.Results) != 3 {
+		t.Fatalf("first refresh: %s, want 2 updated, 0 unchanged, 1 failed", report.Summary())
+	}
+	want := map[string]RefreshStatus{"roma": Updated, "napoli": Updated, "gone": Failed}
+	for _, res := range report.Results {
+		if res.Status != want[res.VenueID] {
+			t.Errorf("status of %s = %s, want %s", res.VenueID, res.Status, want[res.VenueID])
+		}
+	}
+
+	history, err := VenueHistory("roma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, c := range history {
+		if c.Field == "PhoneNumber" {
+			found = true
+			if c.Old != "+49 89 1111111" || c.New != "+49 89 1234567" || c.Source != venue.FixtureProviderName {
+				t.Errorf("phone number change = %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("history of roma = %+v, want a change of the phone number", history)
+	}
+
+	report, err = UpdateFromPlaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 0 || report.Unchanged != 2 || report.Failed != 1 {
+		t.Errorf("second refresh: %s, want 0 updated, 2 unchanged, 1 failed", report.Summary())
+	}
+	history2, err := VenueHistory("roma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history2) != len(history) {
+		t.Errorf("unchanged refresh added history: %+v", history2)
+	}
+
+	//napoli is closed for good now, so it is never picked
+	for i := 0; i < 50; i++ {
+		v, err := PickNext(PickOptions{Old: true, New: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.VenueID == "napoli" {
+			t.Fatal("PickNext picked the closed venue napoli")
+		}
+	}
+	v, err := PickNext(PickOptions{Old: true, New: true, IncludeClosed: true, Strategy: "uniform"})
+	if err != nil || v.VenueID == "" {
+		t.Errorf("PickNext with closed venues = %q, %v", v.VenueID, err)
+	}
+}
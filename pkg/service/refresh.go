@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+	"golang.org/x/time/rate"
+)
+
+const defaultRefreshConcurrency = 4
+
+//RefreshStatus tells what happened to a venue while refreshing it from its PlaceProvider
+type RefreshStatus string
+
+const (
+	//Updated means the infos of the venue changed and were saved
+	Updated RefreshStatus = "updated"
+	//Unchanged means the PlaceProvider had nothing new for the venue
+	Unchanged RefreshStatus = "unchanged"
+	//Failed means the venue could not be refreshed, Reason tells why
+	Failed RefreshStatus = "failed"
+)
+
+//RefreshResult is the outcome of refreshing a single venue
+type RefreshResult struct {
+	VenueID string
+	Name    string
+	Status  RefreshStatus
+	Reason  string `json:",omitempty"`
+}
+
+//RefreshReport lists the outcome of a refresh for every venue together with the count per status
+type RefreshReport struct {
+	Updated   int
+	Unchanged int
+	Failed    int
+	Results   []RefreshResult
+}
+
+//Summary gives back the counts of the report in one line
+func (r RefreshReport) Summary() string {
+	return strconv.Itoa(r.Updated) + " updated, " + strconv.Itoa(r.Unchanged) + " unchanged, " +
+		strconv.Itoa(r.Failed) + " failed"
+}
+
+func (r *RefreshReport) add(res RefreshResult) {
+	switch res.Status {
+	case Updated:
+		r.Updated++
+	case Unchanged:
+		r.Unchanged++
+	case Failed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, res)
+}
+
+var refreshconcurrency = defaultRefreshConcurrency
+var refreshlimiter = rate.NewLimiter(rate.Inf, 1)
+
+//refreshmutex keeps a refresh started by hand and one of the scheduler from running at the same time
+var refreshmutex sync.Mutex
+
+//SetRefreshLimits sets how many venues are looked up at the same time and how many requests per second
+//may be sent to the PlaceProvider. Zero keeps the default of 4 venues at once without a rate limit
+func SetRefreshLimits(concurrency int, persecond float64) {
+	if concurrency < 1 {
+		concurrency = defaultRefreshConcurrency
+	}
+	limit := rate.Inf
+	if persecond > 0 {
+		limit = rate.Limit(persecond)
+	}
+	refreshconcurrency = concurrency
+	refreshlimiter = rate.NewLimiter(limit, 1)
+}
+
+//UpdateFromPlaces refreshes the data of all venues with a PlaceID of the configured PlaceProvider.
+//A failing venue does not stop the others, the report tells what happened to each of them
+func UpdateFromPlaces() (RefreshReport, error) {
+	return refresh(nil)
+}
+
+//refresh refreshes the venues of the configured PlaceProvider for which due returns true, or all if due is nil
+func refresh(due func(v venue.Venue) bool) (RefreshReport, error) {
+	refreshmutex.Lock()
+	defer refreshmutex.Unlock()
+	report := RefreshReport{Results: []RefreshResult{}}
+	all, err := store.Query(venue.Query{OnlyWithPlaceID: true, PlaceProvider: venue.PlaceProviderName()})
+	if err != nil {
+		return report, err
+	}
+	var vv []venue.Venue
+	for _, v := range all {
+		if due == nil || due(v) {
+			vv = append(vv, v)
+		}
+	}
+
+	fetched := make([]venue.Venue, len(vv))
+	errs := make([]error, len(vv))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < refreshconcurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				err := refreshlimiter.Wait(context.Background())
+				if err == nil {
+					fetched[i] = vv[i]
+					err = fetched[i].UpdateInfos()
+				}
+				errs[i] = err
+			}
+		}()
+	}
+	for i := range vv {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	//saving is done one after another, the stores don't gain anything from parallel writes
+	for i, v := range vv {
+		res := RefreshResult{VenueID: v.VenueID, Name: v.Name}
+		var changes []venue.Change
+		if errs[i] == nil {
+			changes = venue.DiffPlaceInfos(v, fetched[i], v.PlaceProvider, fetched[i].LastRefresh)
+		}
+		switch {
+		case errs[i] != nil:
+			res.Status = Failed
+			res.Reason = errs[i].Error()
+		case len(changes) == 0:
+			res.Status = Unchanged
+			err := store.MarkRefreshed(v.VenueID, fetched[i].LastRefresh)
+			if err != nil {
+				res.Status = Failed
+				res.Reason = "Error saving venue: " + err.Error()
+			}
+		default:
+			res.Status = Updated
+			f := fetched[i]
+			_, err := store.Update(v.VenueID, func(stored *venue.Venue) error {
+				stored.OpeningHours = f.OpeningHours
+				stored.OpeningHoursText = f.OpeningHoursText
+				stored.Website = f.Website
+				stored.PhoneNumber = f.PhoneNumber
+				stored.BusinessStatus = f.BusinessStatus
+				stored.LastRefresh = f.LastRefresh
+				return nil
+			})
+			if err != nil {
+				res.Status = Failed
+				res.Reason = "Error saving venue: " + err.Error()
+				break
+			}
+			err = store.AddHistory(v.VenueID, changes)
+			if err != nil {
+				res.Status = Failed
+				res.Reason = "Error saving history: " + err.Error()
+			}
+		}
+		report.add(res)
+	}
+	return report, nil
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//defaultStaleAfter is used to tell stale venues apart if there is no background refresh
+const defaultStaleAfter = 7 * 24 * time.Hour
+
+//Schedule tells how often the venues are refreshed in the background and when the PlaceProvider is left alone.
+//The quiet hours are the time since midnight in the configured timezone, they may span midnight like 22:00 to 07:00
+type Schedule struct {
+	Interval   time.Duration
+	QuietStart time.Duration
+	QuietEnd   time.Duration
+}
+
+var schedule Schedule
+
+//NewSchedule parses the interval as duration like 24h and the quiet hours as times like 22:00.
+//An empty interval disables the background refresh, no quiet hours mean it may run at any time
+func NewSchedule(interval string, quietstart string, quietend string) (Schedule, error) {
+	var s Schedule
+	var err error
+	if interval != "" {
+		s.Interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return s, errors.New("Error parsing interval: " + err.Error())
+		}
+		if s.Interval < time.Minute {
+			return s, errors.New("Interval " + interval + " is shorter than a minute")
+		}
+	}
+	s.QuietStart, err = parseTimeOfDay(quietstart)
+	if err != nil {
+		return s, errors.New("Error parsing start of quiet hours: " + err.Error())
+	}
+	s.QuietEnd, err = parseTimeOfDay(quietend)
+	if err != nil {
+		return s, errors.New("Error parsing end of quiet hours: " + err.Error())
+	}
+	return s, nil
+}
+
+//parseTimeOfDay parses a time like 22:00 as the time since midnight, an empty string is midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+//quiet checks if t is inside the quiet hours
+func (s Schedule) quiet(t time.Time) bool {
+	if s.QuietStart == s.QuietEnd {
+		return false
+	}
+	sincemidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if s.QuietStart < s.QuietEnd {
+		return sincemidnight >= s.QuietStart && sincemidnight < s.QuietEnd
+	}
+	return sincemidnight >= s.QuietStart || sincemidnight < s.QuietEnd
+}
+
+//StaleAfter gives back how old the infos of a venue may get before they are shown as stale. With a background
+//refresh that is twice its interval, so a venue is only stale if it missed a refresh
+func StaleAfter() time.Duration {
+	if schedule.Interval == 0 {
+		return defaultStaleAfter
+	}
+	return 2 * schedule.Interval
+}
+
+//StartScheduler refreshes the venues in the background, every venue is refreshed once its LastRefresh is older than
+//the interval. A venue that failed is tried again after the interval aswell. Nothing is started if the interval is zero
+func StartScheduler(s Schedule) {
+	schedule = s
+	if s.Interval == 0 {
+		return
+	}
+	go runScheduler(s)
+}
+
+//minSchedulerSleep keeps the scheduler from spinning if a venue is due again right away
+const minSchedulerSleep = time.Minute
+
+//runScheduler refreshes the due venues and then sleeps until the next venue is due or the quiet hours end,
+//but never longer than the interval, so venues added meanwhile wait at most that long
+func runScheduler(s Schedule) {
+	failed := make(map[string]time.Time)
+	for {
+		now := Now()
+		next := now.Add(s.Interval)
+		if s.quiet(now) {
+			next = s.quietEnd(now)
+		} else {
+			report, err := refresh(func(v venue.Venue) bool {
+				return !s.due(v, failed).After(now)
+			})
+			if err != nil {
+				log.Println("Error refreshing venues:", err)
+			}
+			for _, res := range report.Results {
+				if res.Status == Failed {
+					failed[res.VenueID] = now
+					log.Println("Error refreshing venue", res.VenueID+":", res.Reason)
+					continue
+				}
+				delete(failed, res.VenueID)
+			}
+			if len(report.Results) > 0 {
+				log.Println("Refreshed venues:", report.Summary())
+			}
+			vv, err := store.Query(venue.Query{OnlyWithPlaceID: true, PlaceProvider: venue.PlaceProviderName()})
+			if err != nil {
+				log.Println("Error looking for the next venue to refresh:", err)
+			}
+			next = s.nextRefresh(vv, failed, now)
+		}
+		wait := next.Sub(now)
+		if wait < minSchedulerSleep {
+			wait = minSchedulerSleep
+		}
+		time.Sleep(wait)
+	}
+}
+
+//due gives back when the venue is to be refreshed, an interval after its LastRefresh or after it failed
+func (s Schedule) due(v venue.Venue, failed map[string]time.Time) time.Time {
+	last := v.LastRefresh
+	if failed[v.VenueID].After(last) {
+		last = failed[v.VenueID]
+	}
+	return last.Add(s.Interval)
+}
+
+//nextRefresh gives back when the first of the venues is due, at the latest an interval after now
+func (s Schedule) nextRefresh(vv []venue.Venue, failed map[string]time.Time, now time.Time) time.Time {
+	next := now.Add(s.Interval)
+	for _, v := range vv {
+		if due := s.due(v, failed); due.Before(next) {
+			next = due
+		}
+	}
+	return next
+}
+
+//quietEnd gives back the end of the quiet hours following t in the timezone of t
+func (s Schedule) quietEnd(t time.Time) time.Time {
+	end := time.Date(t.Year(), t.Month(), t.Day(), int(s.QuietEnd/time.Hour), int(s.QuietEnd%time.Hour/time.Minute), 0, 0,
+		t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+func TestScheduleQuiet(t *testing.T) {
+	s, err := NewSchedule("24h", "22:00", "07:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		t     time.Time
+		quiet bool
+	}{
+		{time.Date(2020, 6, 1, 21, 59, 0, 0, berlin), false},
+		{time.Date(2020, 6, 1, 22, 0, 0, 0, berlin), true},
+		{time.Date(2020, 6, 2, 6, 59, 0, 0, berlin), true},
+		{time.Date(2020, 6, 2, 7, 0, 0, 0, berlin), false},
+		//21:30 in UTC is already 23:30 in the configured timezone
+		{time.Date(2020, 6, 1, 21, 30, 0, 0, time.UTC).In(berlin), true},
+	}
+	for _, tt := range tests {
+		if got := s.quiet(tt.t); got != tt.quiet {
+			t.Errorf("quiet(%s) = %v, want %v", tt.t.Format("15:04 MST"), got, tt.quiet)
+		}
+	}
+
+	defer SetLocation(location)
+	SetLocation(berlin)
+	if Now().Location() != berlin {
+		t.Errorf("Now() is in %s, want the configured timezone", Now().Location())
+	}
+}
+
+func TestScheduleNextRefresh(t *testing.T) {
+	s, err := NewSchedule("24h", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	vv := []venue.Venue{
+		{VenueID: "a", LastRefresh: now.Add(-2 * time.Hour)},
+		{VenueID: "b", LastRefresh: now.Add(-20 * time.Hour)},
+		{VenueID: "c", LastRefresh: now.Add(-30 * time.Hour)},
+	}
+	failed := map[string]time.Time{"c": now.Add(-time.Hour)}
+	if got, want := s.nextRefresh(vv, failed, now), now.Add(4*time.Hour); !got.Equal(want) {
+		t.Errorf("nextRefresh = %s, want %s when b is due", got, want)
+	}
+	if got, want := s.nextRefresh(nil, nil, now), now.Add(24*time.Hour); !got.Equal(want) {
+		t.Errorf("nextRefresh without venues = %s, want %s", got, want)
+	}
+	if got, want := s.nextRefresh(vv[2:], nil, now), now.Add(-6*time.Hour); !got.Equal(want) {
+		t.Errorf("nextRefresh = %s, want %s for an overdue venue", got, want)
+	}
+}
+
+func TestScheduleQuietEnd(t *testing.T) {
+	s, err := NewSchedule("24h", "22:00", "07:30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		t    time.Time
+		want time.Time
+	}{
+		{time.Date(2020, 6, 1, 23, 0, 0, 0, berlin), time.Date(2020, 6, 2, 7, 30, 0, 0, berlin)},
+		{time.Date(2020, 6, 2, 3, 0, 0, 0, berlin), time.Date(2020, 6, 2, 7, 30, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		if got := s.quietEnd(tt.t); !got.Equal(tt.want) {
+			t.Errorf("quietEnd(%s) = %s, want %s", tt.t, got, tt.want)
+		}
+	}
+}
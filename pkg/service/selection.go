@@ -0,0 +1,167 @@
+package service
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/selection"
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+//ErrNoCandidates is returned if there is no venue to choose from
+var ErrNoCandidates = selection.ErrNoCandidates
+
+var strategies map[string]selection.Strategy
+var defaultstrategy string
+
+func init() {
+	SetSelection("", nil)
+}
+
+//SetSelection sets up every registered strategy with its parameters from the config and the strategy used if
+//none is asked for, which defaults to uniform
+func SetSelection(def string, params map[string]map[string]float64) error {
+	if def == "" {
+		def = selection.UniformName
+	}
+	ss := make(map[string]selection.Strategy)
+	for _, name := range selection.Names() {
+		s, err := selection.New(name, params[name])
+		if err != nil {
+			return err
+		}
+		ss[name] = s
+	}
+	for name := range params {
+		if _, ok := ss[name]; !ok {
+			return errors.New("Parameters for unknown strategy " + name)
+		}
+	}
+	if _, ok := ss[def]; !ok {
+		return errors.New("Unknown default strategy " + def)
+	}
+	strategies = ss
+	defaultstrategy = def
+	return nil
+}
+
+//Strategies gives back the names of the strategies to choose the next venue with
+func Strategies() []string {
+	return selection.Names()
+}
+
+//DefaultStrategy gives back the name of the strategy used if none is asked for
+func DefaultStrategy() string {
+	return defaultstrategy
+}
+
+//PickNotVisited gives back a random venue that was never visited. Archived venues are never picked,
+//closed ones only if includeclosed is set
+func PickNotVisited(includeclosed bool) (venue.Venue, error) {
+	oo, err := store.Query(venue.Query{OnlyNotVisited: true, ExcludeClosed: !includeclosed, ExcludeArchived: true})
+	if err != nil {
+		return venue.Venue{}, err
+	}
+	if len(oo) < 1 {
+		return venue.Venue{}, ErrNoCandidates
+	}
+	return oo[rand.Intn(len(oo))], nil
+}
+
+//PickOptions tells PickNext which venues to choose from and how
+type PickOptions struct {
+	//Old includes the visited venues
+	Old bool
+	//New includes the venues that were never visited
+	New bool
+	//Strategy is the name of the strategy choosing among the candidates, empty for the default strategy
+	Strategy string
+	//IncludeClosed includes venues the PlaceProvider reported as closed
+	IncludeClosed bool
+	//At leaves out venues whose opening hours don't cover it, venues without opening hours are kept.
+	//The zero time picks venues regardless of their opening hours
+	At time.Time
+}
+
+//ParseAt reads the time a venue should be open at. It is either given with its offset like 2020-06-01T12:30:00+02:00
+//or as 2020-06-01T12:30 in the configured timezone. An empty string is now
+func ParseAt(s string) (time.Time, error) {
+	if s == "" {
+		return Now(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t.In(location), nil
+	}
+	t, err = time.ParseInLocation("2006-01-02T15:04", s, location)
+	if err != nil {
+		return t, Error{Invalid, errors.New("Error parsing time " + s + ", use a format like 2006-01-02T15:04")}
+	}
+	return t, nil
+}
+
+//openAt keeps the venues which are open at t in the configured timezone or don't have opening hours
+func openAt(venues []venue.Venue, t time.Time) []venue.Venue {
+	var res []venue.Venue
+	t = t.In(location)
+	for _, v := range venues {
+		if open, known := v.OpenAt(t); open || !known {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+//PickNext gives back a random venue chosen as described by the options. Archived venues are never picked
+func PickNext(o PickOptions) (venue.Venue, error) {
+	res, err := ExplainNext(o)
+	return res.Venue, err
+}
+
+//ExplainNext picks the next venue like PickNext and gives back the chance every candidate had aswell
+func ExplainNext(o PickOptions) (selection.Result, error) {
+	q := venue.Query{ExcludeClosed: !o.IncludeClosed, ExcludeArchived: true}
+	switch {
+	case !o.Old && !o.New:
+		return selection.Result{}, ErrNoCandidates
+	case !o.New:
+		q.OnlyVisited = true
+	case !o.Old:
+		q.OnlyNotVisited = true
+	}
+	name := o.Strategy
+	if name == "" {
+		name = defaultstrategy
+	}
+	s, ok := strategies[name]
+	if !ok {
+		return selection.Result{}, Error{Invalid, errors.New("Unknown strategy " + name)}
+	}
+	candiates, err := store.Query(q)
+	if err != nil {
+		return selection.Result{}, err
+	}
+	if !o.At.IsZero() {
+		candiates = openAt(candiates, o.At)
+	}
+	return s.Pick(candiates, Now())
+}
+
+//PlaceCacheStats gives back the hits, misses and estimated cost of the place cache
+func PlaceCacheStats() (venue.PlaceCacheStats, error) {
+	return venue.PlaceCacheStatistics()
+}
+
+//SearchPlaces looks the query up in the Places API and gives back the candidates ranked by relevance
+func SearchPlaces(query string) ([]venue.PlaceCandidate, error) {
+	return venue.SearchPlaces(query)
+}
+
+//VenueFromPlace gives back a new, not yet saved venue filled with the details of the chosen candidate
+func VenueFromPlace(c venue.PlaceCandidate) (venue.Venue, error) {
+	if c.PlaceID == "" {
+		return venue.Venue{}, Error{Invalid, errors.New("Candidate has no Places ID")}
+	}
+	return venue.GetVenuebyCandidate(c)
+}
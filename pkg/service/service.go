@@ -0,0 +1,99 @@
+package service
+
+import (
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+var store venue.VenueStore
+var location = time.Local
+
+//SetLocation sets the timezone the opening hours of the venues are given in
+func SetLocation(l *time.Location) {
+	location = l
+}
+
+//Now gives back the current time in the configured timezone
+func Now() time.Time {
+	return time.Now().In(location)
+}
+
+//SetVenueStore sets the backend the service uses to load and save venues
+func SetVenueStore(s venue.VenueStore) {
+	store = s
+}
+
+//Kind tells what went wrong, so the API and the UI can react the same way without knowing the cause
+type Kind int
+
+const (
+	//Internal is a problem of the server or its storage
+	Internal Kind = iota
+	//Invalid means the input can't be used
+	Invalid
+	//NotFound means the requested venue or visit does not exist
+	NotFound
+	//Conflict means the venue was changed by someone else in the meantime
+	Conflict
+	//PreconditionRequired means a change was requested without telling which revision it is based on
+	PreconditionRequired
+	//Unsupported means the format of the input is not supported
+	Unsupported
+)
+
+//Error is an error of the service together with its Kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e Error) Error() string {
+	return e.Err.Error()
+}
+
+//KindOf gives back the Kind of the error. Errors of the venue package are recognized aswell,
+//everything unknown is Internal
+func KindOf(err error) Kind {
+	switch e := err.(type) {
+	case Error:
+		return e.Kind
+	case venue.ValidationError:
+		return Invalid
+	}
+	switch err {
+	case venue.ErrVenueNotFound, venue.ErrVisitNotFound, ErrNoCandidates:
+		return NotFound
+	case venue.ErrRevisionMismatch:
+		return Conflict
+	}
+	return Internal
+}
+
+//Precondition tells which revisions of a venue a change may be applied to
+type Precondition struct {
+	any       bool
+	revisions []int
+}
+
+//AnyRevision lets a change be applied whatever revision the venue has
+func AnyRevision() Precondition {
+	return Precondition{any: true}
+}
+
+//AtRevision only lets a change be applied if the venue still has one of the given revisions
+func AtRevision(revisions ...int) Precondition {
+	return Precondition{revisions: revisions}
+}
+
+func (p Precondition) check(v venue.Venue) error {
+	if p.any {
+		return nil
+	}
+	for _, r := range p.revisions {
+		if v.Revision == r {
+			return nil
+		}
+	}
+	return venue.ErrRevisionMismatch
+}
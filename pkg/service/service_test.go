@@ -0,0 +1,75 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Kind
+	}{
+		{Error{Conflict, errors.New("conflict")}, Conflict},
+		{venue.ValidationError{Field: "Name"}, Invalid},
+		{venue.ErrVenueNotFound, NotFound},
+		{venue.ErrVisitNotFound, NotFound},
+		{ErrNoCandidates, NotFound},
+		{venue.ErrRevisionMismatch, Conflict},
+		{errors.New("disk full"), Internal},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.err); got != tt.want {
+			t.Errorf("KindOf(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPickNextWithoutCandidates(t *testing.T) {
+	defer setupFixtures(t)()
+	_, err := PickNext(PickOptions{Old: true, New: true})
+	if KindOf(err) != NotFound {
+		t.Errorf("PickNext on an empty store = %v, want a NotFound error", err)
+	}
+}
+
+func TestSetSelectionChecksParameters(t *testing.T) {
+	defer SetSelection("", nil)
+	tests := []struct {
+		def    string
+		params map[string]map[string]float64
+		ok     bool
+	}{
+		{"", nil, true},
+		{"weighted", map[string]map[string]float64{"weighted": {"rating": 2}}, true},
+		{"weighted", map[string]map[string]float64{"weighted": {"ratign": 2}}, false},
+		{"", map[string]map[string]float64{"weigthed": {"rating": 2}}, false},
+		{"random", nil, false},
+	}
+	for _, tt := range tests {
+		err := SetSelection(tt.def, tt.params)
+		if tt.ok != (err == nil) {
+			t.Errorf("SetSelection(%q, %v) = %v, want ok %v", tt.def, tt.params, err, tt.ok)
+		}
+	}
+}
+
+func TestPickNextSkipsVenuesClosedAllWeek(t *testing.T) {
+	defer setupFixtures(t)()
+	err := store.Put(venue.Venue{VenueID: "closed", Name: "Closed", ClosedAllWeek: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	_, err = PickNext(PickOptions{Old: true, New: true, At: at})
+	if KindOf(err) != NotFound {
+		t.Errorf("PickNext at %s = %v, want no candidates", at, err)
+	}
+	v, err := PickNext(PickOptions{Old: true, New: true})
+	if err != nil || v.VenueID != "closed" {
+		t.Errorf("PickNext at any time = %q, %v, want the closed venue", v.VenueID, err)
+	}
+}
@@ -0,0 +1,258 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/philmacfly/wheretoeat/pkg/jsonpatch"
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+const (
+	//MergePatch is the format of a JSON Merge Patch as described in RFC 7396
+	MergePatch = "application/merge-patch+json"
+	//JSONPatch is the format of a JSON Patch as described in RFC 6902
+	JSONPatch = "application/json-patch+json"
+)
+
+//ListVenues gives back all venues sorted by sortby, which is one of name, name-desc, rating and rating-desc
+func ListVenues(sortby string) ([]venue.Venue, error) {
+	vv, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	switch sortby {
+	case "name-desc":
+		sort.Sort(venue.ByNameReverse(vv))
+	case "rating":
+		sort.Sort(venue.ByRating(vv))
+	case "rating-desc":
+		sort.Sort(venue.ByRatingReverse(vv))
+	default:
+		sort.Sort(venue.ByName(vv))
+	}
+	return vv, nil
+}
+
+//GetVenue gives back the venue with the given ID or alias
+func GetVenue(id string) (venue.Venue, error) {
+	return store.Get(id)
+}
+
+//VenueHistory gives back the changes made to the venue by refreshes, the oldest first
+func VenueHistory(id string) ([]venue.Change, error) {
+	return store.History(id)
+}
+
+//CreateVenue validates the venue and saves it with a new ID as its first revision
+func CreateVenue(v venue.Venue) (venue.Venue, error) {
+	err := v.Validate()
+	if err != nil {
+		return v, err
+	}
+	v.VenueID = venue.NewVenueID()
+	v.AssignVisitIDs()
+	v.Revision = 1
+	err = store.Put(v)
+	if err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+//UpdateVenue applies fn to the venue if the precondition is met. The ID and revision of the venue can't be
+//changed by fn and the result has to be valid
+func UpdateVenue(id string, p Precondition, fn func(v *venue.Venue) error) (venue.Venue, error) {
+	return store.Update(id, func(v *venue.Venue) error {
+		err := p.check(*v)
+		if err != nil {
+			return err
+		}
+		venueid := v.VenueID
+		revision := v.Revision
+		err = fn(v)
+		if err != nil {
+			return err
+		}
+		if v.VenueID != venueid {
+			return venue.ValidationError{Field: "VenueID", Message: "must not be changed"}
+		}
+		//the revision is managed by the store
+		v.Revision = revision
+		return v.Validate()
+	})
+}
+
+//PatchVenue applies a patch in the given format to the venue if the precondition is met
+func PatchVenue(id string, p Precondition, format string, patch []byte) (venue.Venue, error) {
+	err := checkPatchFormat(format)
+	if err != nil {
+		return venue.Venue{}, err
+	}
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		var patched venue.StrictVenue
+		err := applyPatch(v, format, patch, &patched)
+		if err != nil {
+			return err
+		}
+		*v = patched.Venue()
+		return nil
+	})
+}
+
+//DeleteVenue removes the venue if the precondition is met
+func DeleteVenue(id string, p Precondition) error {
+	return store.Delete(id, p.check)
+}
+
+//ArchiveVenue archives or restores the venue if the precondition is met
+func ArchiveVenue(id string, p Precondition, archived bool) (venue.Venue, error) {
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		v.Archived = archived
+		return nil
+	})
+}
+
+//AddException adds the exception to the venue if the precondition is met
+func AddException(id string, p Precondition, e venue.Exception) (venue.Venue, error) {
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		v.Exceptions = append(v.Exceptions, e)
+		return nil
+	})
+}
+
+//DeleteException removes the exception at index from the venue if the precondition is met
+func DeleteException(id string, p Precondition, index int) (venue.Venue, error) {
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		if index < 0 || index >= len(v.Exceptions) {
+			return Error{NotFound, errors.New("Exception not found")}
+		}
+		v.Exceptions = append(v.Exceptions[:index], v.Exceptions[index+1:]...)
+		return nil
+	})
+}
+
+//UpcomingHolidays gives back the next public holidays starting today in the configured timezone
+func UpcomingHolidays(max int) []venue.Holiday {
+	return venue.UpcomingHolidays(Now(), max)
+}
+
+//AddVisits adds the visits to the venue if the precondition is met
+func AddVisits(id string, p Precondition, visits []venue.Visit) (venue.Venue, error) {
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		v.Visits = append(v.Visits, visits...)
+		return nil
+	})
+}
+
+//GetVisit gives back the visit together with the venue it belongs to
+func GetVisit(id string, visitid string) (venue.Visit, venue.Venue, error) {
+	v, err := store.Get(id)
+	if err != nil {
+		return venue.Visit{}, v, err
+	}
+	index := v.FindVisit(visitid)
+	if index < 0 {
+		return venue.Visit{}, v, venue.ErrVisitNotFound
+	}
+	return v.Visits[index], v, nil
+}
+
+//UpdateVisit applies fn to the visit if the precondition is met for its venue. The ID of the visit can't be changed
+func UpdateVisit(id string, visitid string, p Precondition, fn func(vi *venue.Visit) error) (venue.Visit, venue.Venue, error) {
+	var visit venue.Visit
+	v, err := UpdateVenue(id, p, func(v *venue.Venue) error {
+		index := v.FindVisit(visitid)
+		if index < 0 {
+			return venue.ErrVisitNotFound
+		}
+		visit = v.Visits[index]
+		err := fn(&visit)
+		if err != nil {
+			return err
+		}
+		if visit.VisitID != visitid {
+			return venue.ValidationError{Field: "VisitID", Message: "must not be changed"}
+		}
+		v.Visits[index] = visit
+		return nil
+	})
+	return visit, v, err
+}
+
+//PatchVisit applies a patch in the given format to the visit if the precondition is met for its venue
+func PatchVisit(id string, visitid string, p Precondition, format string, patch []byte) (venue.Visit, venue.Venue, error) {
+	err := checkPatchFormat(format)
+	if err != nil {
+		return venue.Visit{}, venue.Venue{}, err
+	}
+	return UpdateVisit(id, visitid, p, func(vi *venue.Visit) error {
+		var patched venue.StrictVisit
+		err := applyPatch(vi, format, patch, &patched)
+		if err != nil {
+			return err
+		}
+		*vi = venue.Visit(patched)
+		return nil
+	})
+}
+
+//DeleteVisit removes the visit from its venue if the precondition is met
+func DeleteVisit(id string, visitid string, p Precondition) (venue.Venue, error) {
+	return UpdateVenue(id, p, func(v *venue.Venue) error {
+		index := v.FindVisit(visitid)
+		if index < 0 {
+			return venue.ErrVisitNotFound
+		}
+		v.Visits = append(v.Visits[:index:index], v.Visits[index+1:]...)
+		return nil
+	})
+}
+
+//Problems gives back everything that went wrong while loading the venues
+func Problems() ([]venue.StoreProblem, error) {
+	_, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	problems := []venue.StoreProblem{}
+	if hr, ok := store.(venue.HealthReporter); ok {
+		problems = append(problems, hr.Problems()...)
+	}
+	return problems, nil
+}
+
+func checkPatchFormat(format string) error {
+	switch format {
+	case MergePatch, JSONPatch:
+		return nil
+	}
+	return Error{Unsupported, errors.New("Unsupported patch format: " + format)}
+}
+
+//applyPatch applies the patch to the JSON form of value and decodes the result into patched.
+//patched must not have an UnmarshalJSON of its own, else unknown fields get through
+func applyPatch(value interface{}, format string, patch []byte, patched interface{}) error {
+	doc, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case JSONPatch:
+		doc, err = jsonpatch.Apply(doc, patch)
+	default:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	}
+	if err != nil {
+		return Error{Invalid, err}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(patched)
+	if err != nil {
+		return Error{Invalid, errors.New("Patched document can't be decoded: " + err.Error())}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/philmacfly/wheretoeat/pkg/venue"
+)
+
+func TestApplyPatchRejectsUnknownFields(t *testing.T) {
+	v := venue.Venue{VenueID: "v1", Name: "Roma", Visits: []venue.Visit{{VisitID: "a"}}}
+	tests := []struct {
+		name   string
+		format string
+		patch  string
+		ok     bool
+	}{
+		{"known field", MergePatch, `{"Name":"Napoli"}`, true},
+		{"known field in other case", MergePatch, `{"name":"Napoli"}`, true},
+		{"nested known field", MergePatch, `{"OpeningHours":{"periods":[]}}`, true},
+		{"typo", MergePatch, `{"Nmae":"Napoli"}`, false},
+		{"typo in visit", JSONPatch, `[{"op":"add","path":"/Visits/0/Nmae","value":"x"}]`, false},
+		{"typo in opening hours", MergePatch, `{"OpeningHours":{"perods":[]}}`, false},
+		{"legacy GooglePlaceID", MergePatch, `{"GooglePlaceID":"abc"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patched venue.StrictVenue
+			err := applyPatch(v, tt.format, []byte(tt.patch), &patched)
+			if tt.ok && err != nil {
+				t.Errorf("applyPatch(%s) failed: %v", tt.patch, err)
+			}
+			if !tt.ok && KindOf(err) != Invalid {
+				t.Errorf("applyPatch(%s) = %v, want an Invalid error", tt.patch, err)
+			}
+		})
+	}
+
+	var patched venue.StrictVisit
+	err := applyPatch(v.Visits[0], MergePatch, []byte(`{"Notse":"x"}`), &patched)
+	if KindOf(err) != Invalid {
+		t.Errorf("applyPatch on visit = %v, want an Invalid error", err)
+	}
+}
+
+func TestStrictVenueKeepsGooglePlaceID(t *testing.T) {
+	v := venue.Venue{VenueID: "v1", Name: "Roma", Visits: []venue.Visit{{VisitID: "a", Rating: 4}}}
+	var patched venue.StrictVenue
+	err := applyPatch(v, MergePatch, []byte(`{"GooglePlaceID":"abc"}`), &patched)
+	if err != nil {
+		t.Fatalf("applyPatch failed: %v", err)
+	}
+	got := patched.Venue()
+	if got.PlaceID != "abc" || got.PlaceProvider != venue.GoogleProviderName {
+		t.Errorf("PlaceProvider, PlaceID = %q, %q, want %q, %q", got.PlaceProvider, got.PlaceID, venue.GoogleProviderName, "abc")
+	}
+	if len(got.Visits) != 1 || got.Visits[0].VisitID != "a" || got.Visits[0].Rating != 4 {
+		t.Errorf("Visits = %+v, want the visit unchanged", got.Visits)
+	}
+}
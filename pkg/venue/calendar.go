@@ -0,0 +1,156 @@
+package venue
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const layoutDate = "2006-01-02"
+
+//Exception overrides the weekly opening hours of a venue from From to To, both days included. A closure keeps the
+//venue closed on these days, an opening keeps it open from Open to Close like 1130 and 1400 or the whole day if
+//they are empty
+type Exception struct {
+	From   time.Time
+	To     time.Time
+	Closed bool
+	Open   string `json:",omitempty"`
+	Close  string `json:",omitempty"`
+	Reason string `json:",omitempty"`
+}
+
+//Covers checks if the day of t lies between From and To
+func (e Exception) Covers(t time.Time) bool {
+	day := t.Format(layoutDate)
+	return day >= e.From.Format(layoutDate) && day <= e.To.Format(layoutDate)
+}
+
+//Holiday is a public holiday, venues which are ClosedOnHolidays stay closed on it
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+var holidays = make(map[string]Holiday)
+
+//SetHolidays sets the public holidays used for all venues
+func SetHolidays(hh []Holiday) {
+	m := make(map[string]Holiday)
+	for _, h := range hh {
+		m[h.Date.Format(layoutDate)] = h
+	}
+	holidays = m
+}
+
+//HolidayOn gives back the public holiday on the day of t
+func HolidayOn(t time.Time) (Holiday, bool) {
+	h, ok := holidays[t.Format(layoutDate)]
+	return h, ok
+}
+
+//UpcomingHolidays gives back the public holidays from the day of t on, the next one first
+func UpcomingHolidays(t time.Time, max int) []Holiday {
+	var res []Holiday
+	today := t.Format(layoutDate)
+	for day, h := range holidays {
+		if day >= today {
+			res = append(res, h)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Date.Before(res[j].Date) })
+	if len(res) > max {
+		res = res[:max]
+	}
+	return res
+}
+
+//ExceptionOn gives back the exception covering the day of t. If several do the one added last wins
+func (v Venue) ExceptionOn(t time.Time) (Exception, bool) {
+	for i := len(v.Exceptions) - 1; i >= 0; i-- {
+		if v.Exceptions[i].Covers(t) {
+			return v.Exceptions[i], true
+		}
+	}
+	return Exception{}, false
+}
+
+//ParseICSHolidays reads the events of an iCalendar file, like the public holidays published for a country, as
+//holidays. Events spanning several days give a holiday for every day
+func ParseICSHolidays(r io.Reader) ([]Holiday, error) {
+	var res []Holiday
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		//long lines are folded by starting the following lines with a space or a tab
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, errors.New("Error reading calendar: " + err.Error())
+	}
+
+	var start, end time.Time
+	var name string
+	inevent := false
+	for _, line := range lines {
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		//parameters like in DTSTART;VALUE=DATE:20201225 are not needed
+		key := strings.ToUpper(strings.Split(line[:i], ";")[0])
+		value := line[i+1:]
+		switch key {
+		case "BEGIN":
+			if strings.ToUpper(value) == "VEVENT" {
+				inevent = true
+				start, end, name = time.Time{}, time.Time{}, ""
+			}
+		case "DTSTART", "DTEND":
+			if !inevent {
+				continue
+			}
+			if len(value) < 8 {
+				return nil, errors.New("Invalid date " + value)
+			}
+			d, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, errors.New("Error parsing date: " + err.Error())
+			}
+			if key == "DTSTART" {
+				start = d
+			} else {
+				end = d
+			}
+		case "SUMMARY":
+			if inevent {
+				name = strings.Replace(value, `\,`, ",", -1)
+			}
+		case "END":
+			if !inevent || strings.ToUpper(value) != "VEVENT" {
+				continue
+			}
+			inevent = false
+			if start.IsZero() {
+				return nil, errors.New("Event " + name + " has no start")
+			}
+			//the end date is the first day after the event
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				res = append(res, Holiday{Date: d, Name: name})
+			}
+		}
+	}
+	return res, nil
+}
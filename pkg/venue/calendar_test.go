@@ -0,0 +1,59 @@
+package venue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICSHolidays(t *testing.T) {
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2020, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name string
+		ics  string
+		want []Holiday
+	}{
+		{"date value", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20201225\r\nDTEND;VALUE=DATE:20201226\r\n" +
+			"SUMMARY:Christmas Day\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			[]Holiday{{date(time.December, 25), "Christmas Day"}}},
+		{"without end", "BEGIN:VEVENT\nDTSTART:20200501\nSUMMARY:Labour Day\nEND:VEVENT\n",
+			[]Holiday{{date(time.May, 1), "Labour Day"}}},
+		{"date time", "BEGIN:VEVENT\nDTSTART:20200501T000000Z\nSUMMARY:Labour Day\nEND:VEVENT\n",
+			[]Holiday{{date(time.May, 1), "Labour Day"}}},
+		{"several days", "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20201224\nDTEND;VALUE=DATE:20201227\nSUMMARY:Christmas\nEND:VEVENT\n",
+			[]Holiday{{date(time.December, 24), "Christmas"}, {date(time.December, 25), "Christmas"},
+				{date(time.December, 26), "Christmas"}}},
+		{"folded and escaped", "BEGIN:VEVENT\nDTSTART:20201003\nSUMMARY:Day of German\n  Unity\\, national holiday\nEND:VEVENT\n",
+			[]Holiday{{date(time.October, 3), "Day of German Unity, national holiday"}}},
+		{"lower case and other components", "BEGIN:VTIMEZONE\nDTSTART:19700101\nEND:VTIMEZONE\n" +
+			"begin:vevent\ndtstart:20200101\nsummary:New Year\nend:vevent\nBEGIN:VEVENT\nDTSTART:20200106\nSUMMARY:Epiphany\nEND:VEVENT\n",
+			[]Holiday{{date(time.January, 1), "New Year"}, {date(time.January, 6), "Epiphany"}}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hh, err := ParseICSHolidays(strings.NewReader(tt.ics))
+			if err != nil {
+				t.Fatalf("ParseICSHolidays failed: %v", err)
+			}
+			if !reflect.DeepEqual(hh, tt.want) {
+				t.Errorf("ParseICSHolidays() = %+v, want %+v", hh, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICSHolidaysErrors(t *testing.T) {
+	for _, ics := range []string{
+		"BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\n",
+		"BEGIN:VEVENT\nDTSTART:2020\nEND:VEVENT\n",
+		"BEGIN:VEVENT\nDTSTART:2020-12-25\nEND:VEVENT\n",
+	} {
+		if hh, err := ParseICSHolidays(strings.NewReader(ics)); err == nil {
+			t.Errorf("ParseICSHolidays(%q) = %+v, want an error", ics, hh)
+		}
+	}
+}
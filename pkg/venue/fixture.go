@@ -0,0 +1,82 @@
+package venue
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+//FixtureProviderName is the PlaceProvider of venues found in a fixture folder
+const FixtureProviderName = "fixture"
+
+//FixtureProvider serves places from JSON files instead of a network API, so the places flows can be tried
+//and tested offline. A search for "Pizza, Main St" reads search/pizza-main-st.json holding a list of
+//PlaceCandidate, the details of a place are read from details/<PlaceID>.json holding PlaceDetails.
+//The files are read on every request, so they can be changed while the server is running
+type FixtureProvider struct {
+	folder string
+}
+
+//NewFixtureProvider sets up a provider serving the fixtures inside folder
+func NewFixtureProvider(folder string) (*FixtureProvider, error) {
+	info, err := os.Stat(folder)
+	if err != nil {
+		return nil, errors.New("Error opening fixture folder: " + err.Error())
+	}
+	if !info.IsDir() {
+		return nil, errors.New("Fixture folder " + folder + " is no folder")
+	}
+	return &FixtureProvider{folder: folder}, nil
+}
+
+//Name gives back FixtureProviderName
+func (f *FixtureProvider) Name() string {
+	return FixtureProviderName
+}
+
+//Search gives back the candidates saved for the query, a query without a file finds nothing
+func (f *FixtureProvider) Search(query string) ([]PlaceCandidate, error) {
+	var res []PlaceCandidate
+	err := f.load(filepath.Join("search", fixtureKey(query)+".json"), &res)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return res, err
+}
+
+//Details gives back the details saved for the place
+func (f *FixtureProvider) Details(placeid string) (PlaceDetails, error) {
+	var res PlaceDetails
+	if placeid == "" || strings.HasPrefix(placeid, ".") || strings.ContainsAny(placeid, `/\`) {
+		return res, errors.New("Invalid fixture place ID: " + placeid)
+	}
+	err := f.load(filepath.Join("details", placeid+".json"), &res)
+	if os.IsNotExist(err) {
+		return res, errors.New("Place " + placeid + " not found in fixtures")
+	}
+	return res, err
+}
+
+func (f *FixtureProvider) load(name string, v interface{}) error {
+	file, err := os.Open(filepath.Join(f.folder, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(v)
+	if err != nil {
+		return errors.New("Error decoding fixture " + name + ": " + err.Error())
+	}
+	return nil
+}
+
+//fixtureKey turns a query into a file name by lower casing it and joining its words with dashes
+func fixtureKey(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
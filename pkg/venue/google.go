@@ -0,0 +1,90 @@
+package venue
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"googlemaps.github.io/maps"
+)
+
+//GoogleProviderName is the PlaceProvider of venues found with the Google Places API
+const GoogleProviderName = "google"
+
+const detailqueryfields = "opening_hours,website,international_phone_number,business_status"
+
+//GoogleProvider looks up places with the Google Places API
+type GoogleProvider struct {
+	client *maps.Client
+	fields []maps.PlaceDetailsFieldMask
+}
+
+func parseDetailFields(fields string) ([]maps.PlaceDetailsFieldMask, error) {
+	var res []maps.PlaceDetailsFieldMask
+	for _, s := range strings.Split(fields, ",") {
+		f, err := maps.ParsePlaceDetailsFieldMask(s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+//NewGoogleProvider Setups the API key for the client and the fields for the detail queries
+func NewGoogleProvider(apikey string) (*GoogleProvider, error) {
+	client, err := maps.NewClient(maps.WithAPIKey(apikey))
+	if err != nil {
+		return nil, errors.New("Error setting up client:" + err.Error())
+	}
+	fields, err := parseDetailFields(detailqueryfields)
+	if err != nil {
+		return nil, errors.New("Error populating seardetail fieldmask:" + err.Error())
+	}
+	return &GoogleProvider{client: client, fields: fields}, nil
+}
+
+//Name gives back GoogleProviderName
+func (g *GoogleProvider) Name() string {
+	return GoogleProviderName
+}
+
+//Search queries the text search of the Places API
+func (g *GoogleProvider) Search(query string) ([]PlaceCandidate, error) {
+	searchRequest := &maps.TextSearchRequest{
+		Query: query,
+	}
+
+	searchResp, err := g.client.TextSearch(context.Background(), searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	var res []PlaceCandidate
+	for _, r := range searchResp.Results {
+		res = append(res, PlaceCandidate{PlaceID: r.PlaceID, Name: r.Name, Address: r.FormattedAddress,
+			Rating: float64(r.Rating)})
+	}
+	return res, nil
+}
+
+//Details queries the place details of the Places API
+func (g *GoogleProvider) Details(placeid string) (PlaceDetails, error) {
+	detailRequest := &maps.PlaceDetailsRequest{
+		PlaceID: placeid,
+		Fields:  g.fields,
+	}
+
+	detailResp, err := g.client.PlaceDetails(context.Background(), detailRequest)
+	if err != nil {
+		return PlaceDetails{}, err
+	}
+
+	res := PlaceDetails{Website: detailResp.Website, PhoneNumber: detailResp.InternationalPhoneNumber,
+		BusinessStatus: detailResp.BusinessStatus}
+	//places without opening hours come without the field
+	if detailResp.OpeningHours != nil {
+		res.OpeningHours = *detailResp.OpeningHours
+		res.OpeningHoursText = detailResp.OpeningHours.WeekdayText
+	}
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package venue
+
+import (
+	"strings"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+//Change records that a field of a venue got a new value, Source tells where the new value came from
+type Change struct {
+	Field  string
+	Old    string
+	New    string
+	Time   time.Time
+	Source string
+}
+
+//DiffPlaceInfos compares the infos of a venue which are refreshed from a PlaceProvider and gives back a Change
+//for every field that differs. OpenNow is left out, it only tells how things were at the time of the request
+func DiffPlaceInfos(before Venue, after Venue, source string, at time.Time) []Change {
+	var changes []Change
+	add := func(field string, b string, a string) {
+		if b != a {
+			changes = append(changes, Change{Field: field, Old: b, New: a, Time: at, Source: source})
+		}
+	}
+	add("OpeningHours", formatPeriods(before.OpeningHours.Periods), formatPeriods(after.OpeningHours.Periods))
+	add("OpeningHoursText", strings.Join(before.OpeningHoursText, "\n"), strings.Join(after.OpeningHoursText, "\n"))
+	add("Website", before.Website, after.Website)
+	add("PhoneNumber", before.PhoneNumber, after.PhoneNumber)
+	add("BusinessStatus", before.BusinessStatus, after.BusinessStatus)
+	return changes
+}
+
+//formatPeriods writes the opening hours like "Mon 11:30-14:00, Fri 22:00-Sat 02:00"
+func formatPeriods(pp []maps.OpeningHoursPeriod) string {
+	var res []string
+	for _, p := range pp {
+		s := p.Open.Day.String()[:3] + " " + formatPeriodTime(p.Open.Time)
+		if p.Close.Time != "" {
+			s += "-"
+			if p.Close.Day != p.Open.Day {
+				s += p.Close.Day.String()[:3] + " "
+			}
+			s += formatPeriodTime(p.Close.Time)
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, ", ")
+}
+
+func formatPeriodTime(t string) string {
+	if len(t) != 4 {
+		return t
+	}
+	return t[:2] + ":" + t[2:]
+}
@@ -0,0 +1,144 @@
+package venue
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+const minutesPerDay = 24 * 60
+const minutesPerWeek = 7 * minutesPerDay
+
+//OpenAt tells if the venue is open at t, which is taken in its own location. An exception covering the day of t
+//decides first, then a public holiday if the venue is ClosedOnHolidays and then the weekly opening hours.
+//Periods may span midnight or the end of the week, a period without closing time is open around the clock.
+//known is false if the venue has no usable opening hours and isn't ClosedAllWeek, then nothing can be said about it
+func (v Venue) OpenAt(t time.Time) (open bool, known bool) {
+	if e, ok := v.ExceptionOn(t); ok {
+		return e.openAt(t), true
+	}
+	if _, ok := HolidayOn(t); ok && v.ClosedOnHolidays {
+		return false, true
+	}
+	at := int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute()
+	for _, p := range v.OpeningHours.Periods {
+		start, length, ok := periodMinutes(p)
+		if !ok {
+			continue
+		}
+		known = true
+		if (at-start+minutesPerWeek)%minutesPerWeek < length {
+			return true, true
+		}
+	}
+	return false, known || v.ClosedAllWeek
+}
+
+//periodMinutes gives back the minute of the week the period opens at and for how many minutes it is open.
+//Periods may span midnight or the end of the week, a period without closing time is open around the clock
+func periodMinutes(p maps.OpeningHoursPeriod) (int, int, bool) {
+	start, ok := minuteOfWeek(p.Open)
+	if !ok {
+		return 0, 0, false
+	}
+	if p.Close.Time == "" {
+		return start, minutesPerWeek, true
+	}
+	end, ok := minuteOfWeek(p.Close)
+	if !ok {
+		return 0, 0, false
+	}
+	//overnight hours entered by hand keep the day they start on, like Friday 22:00-02:00
+	if end <= start && p.Close.Day == p.Open.Day {
+		end += minutesPerDay
+	}
+	length := (end - start + minutesPerWeek) % minutesPerWeek
+	if length == 0 {
+		length = minutesPerWeek
+	}
+	return start, length, true
+}
+
+//SetOpeningHours replaces the weekly opening hours of the venue, closedallweek only counts without periods. If they
+//changed, the texts describing them are written anew from the periods, so they don't keep telling the old opening hours
+func (v *Venue) SetOpeningHours(pp []maps.OpeningHoursPeriod, closedallweek bool) {
+	closedallweek = closedallweek && len(pp) == 0
+	same := len(pp) == 0 && len(v.OpeningHours.Periods) == 0 || reflect.DeepEqual(pp, v.OpeningHours.Periods)
+	if same && closedallweek == v.ClosedAllWeek {
+		return
+	}
+	v.OpeningHours.Periods = pp
+	v.ClosedAllWeek = closedallweek
+	v.OpeningHours.WeekdayText = weekdayText(pp, closedallweek)
+	v.OpeningHoursText = v.OpeningHours.WeekdayText
+}
+
+//weekdayText describes the periods a line per day like the Places API does, "Monday: 11:30–14:00, 17:00–23:00"
+func weekdayText(pp []maps.OpeningHoursPeriod, closedallweek bool) []string {
+	var week [7][]osmSpan
+	known := closedallweek
+	for _, p := range pp {
+		start, length, ok := periodMinutes(p)
+		if !ok {
+			continue
+		}
+		known = true
+		if length == minutesPerWeek {
+			for d := range week {
+				week[d] = []osmSpan{{0, minutesPerDay}}
+			}
+			break
+		}
+		day := start / minutesPerDay
+		week[day] = append(week[day], osmSpan{start % minutesPerDay, start%minutesPerDay + length})
+	}
+	if !known {
+		return nil
+	}
+	for _, spans := range week {
+		sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	}
+	return osmWeekdayText(week)
+}
+
+//openAt checks the time of day of t against the exception, an opening closing before it opens is open until midnight
+func (e Exception) openAt(t time.Time) bool {
+	if e.Closed {
+		return false
+	}
+	if e.Open == "" {
+		return true
+	}
+	start, ok := minuteOfWeek(maps.OpeningHoursOpenClose{Time: e.Open})
+	if !ok {
+		return false
+	}
+	end, ok := minuteOfWeek(maps.OpeningHoursOpenClose{Time: e.Close})
+	if !ok {
+		return false
+	}
+	if end <= start {
+		end = minutesPerDay
+	}
+	at := t.Hour()*60 + t.Minute()
+	return at >= start && at < end
+}
+
+//minuteOfWeek reads the day and time like 1730 as minutes since Sunday midnight
+func minuteOfWeek(oc maps.OpeningHoursOpenClose) (int, bool) {
+	if len(oc.Time) != 4 {
+		return 0, false
+	}
+	hh, err := strconv.Atoi(oc.Time[:2])
+	if err != nil || hh > 24 {
+		return 0, false
+	}
+	mm, err := strconv.Atoi(oc.Time[2:])
+	if err != nil || mm > 59 {
+		return 0, false
+	}
+	return int(oc.Day)*minutesPerDay + hh*60 + mm, true
+}
@@ -0,0 +1,136 @@
+package venue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+func period(openday time.Weekday, open string, closeday time.Weekday, close string) maps.OpeningHoursPeriod {
+	return maps.OpeningHoursPeriod{
+		Open:  maps.OpeningHoursOpenClose{Day: openday, Time: open},
+		Close: maps.OpeningHoursOpenClose{Day: closeday, Time: close},
+	}
+}
+
+//at gives back a time in June 2020, which starts on a Monday
+func at(day int, hour int, minute int) time.Time {
+	return time.Date(2020, time.June, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestOpenAt(t *testing.T) {
+	defer SetHolidays(nil)
+	SetHolidays([]Holiday{{Date: at(1, 0, 0), Name: "Whit Monday"}})
+
+	places := Venue{OpeningHours: maps.OpeningHours{Periods: []maps.OpeningHoursPeriod{
+		period(time.Monday, "1130", time.Monday, "1430"),
+		period(time.Friday, "1730", time.Saturday, "0100"),
+		//over the end of the week, Sunday is the first day
+		period(time.Saturday, "2200", time.Sunday, "0200"),
+	}}}
+	byhand := Venue{OpeningHours: maps.OpeningHours{Periods: []maps.OpeningHoursPeriod{
+		period(time.Tuesday, "2200", time.Tuesday, "0200"),
+	}}}
+	always := Venue{OpeningHours: maps.OpeningHours{Periods: []maps.OpeningHoursPeriod{
+		{Open: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0000"}},
+	}}}
+	holidays := places
+	holidays.ClosedOnHolidays = true
+	exceptions := places
+	exceptions.Exceptions = []Exception{
+		{From: at(1, 0, 0), To: at(1, 0, 0), Closed: true, Reason: "Vacation"},
+		{From: at(2, 0, 0), To: at(3, 0, 0), Open: "1800", Close: "2000"},
+		{From: at(4, 0, 0), To: at(4, 0, 0)},
+		{From: at(6, 0, 0), To: at(6, 0, 0), Open: "2200", Close: "0100"},
+	}
+
+	tests := []struct {
+		name  string
+		v     Venue
+		t     time.Time
+		open  bool
+		known bool
+	}{
+		{"inside period", places, at(1, 12, 0), true, true},
+		{"at opening", places, at(1, 11, 30), true, true},
+		{"before opening", places, at(1, 11, 29), false, true},
+		{"at closing", places, at(1, 14, 30), false, true},
+		{"other day", places, at(2, 12, 0), false, true},
+		{"overnight before midnight", places, at(5, 23, 0), true, true},
+		{"overnight after midnight", places, at(6, 0, 30), true, true},
+		{"overnight at closing", places, at(6, 1, 0), false, true},
+		{"end of week before midnight", places, at(6, 23, 0), true, true},
+		{"end of week after midnight", places, at(7, 1, 59), true, true},
+		{"end of week at closing", places, at(7, 2, 0), false, true},
+		{"overnight by hand before midnight", byhand, at(2, 23, 0), true, true},
+		{"overnight by hand after midnight", byhand, at(3, 1, 0), true, true},
+		{"overnight by hand after closing", byhand, at(3, 3, 0), false, true},
+		{"overnight by hand before opening", byhand, at(2, 21, 0), false, true},
+		{"always open", always, at(3, 4, 0), true, true},
+		{"no opening hours", Venue{}, at(1, 12, 0), false, false},
+		{"closed all week", Venue{ClosedAllWeek: true}, at(1, 12, 0), false, true},
+		{"holiday ignored", places, at(1, 12, 0), true, true},
+		{"closed on holidays", holidays, at(1, 12, 0), false, true},
+		{"no holiday", holidays, at(5, 23, 0), true, true},
+		{"closure", exceptions, at(1, 12, 0), false, true},
+		{"exception opening", exceptions, at(2, 19, 0), true, true},
+		{"outside exception opening", exceptions, at(3, 12, 0), false, true},
+		{"exception opening whole day", exceptions, at(4, 3, 0), true, true},
+		{"exception opening until midnight", exceptions, at(6, 23, 30), true, true},
+		{"exception opening not the next day", exceptions, at(6, 0, 30), false, true},
+		{"without exception", exceptions, at(5, 23, 0), true, true},
+	}
+	for _, tt := range tests {
+		open, known := tt.v.OpenAt(tt.t)
+		if open != tt.open || known != tt.known {
+			t.Errorf("%s: OpenAt(%s) = %v, %v, want %v, %v", tt.name, tt.t.Format("Mon 15:04"), open, known, tt.open, tt.known)
+		}
+	}
+}
+
+func TestSetOpeningHours(t *testing.T) {
+	v := Venue{
+		OpeningHours: maps.OpeningHours{
+			Periods:     []maps.OpeningHoursPeriod{period(time.Monday, "1130", time.Monday, "1430")},
+			WeekdayText: []string{"Monday: 11:30 AM – 2:30 PM"},
+		},
+		OpeningHoursText: []string{"Monday: 11:30 AM – 2:30 PM"},
+	}
+	//the same periods keep the texts of the PlaceProvider
+	v.SetOpeningHours([]maps.OpeningHoursPeriod{period(time.Monday, "1130", time.Monday, "1430")}, false)
+	if v.OpeningHoursText[0] != "Monday: 11:30 AM – 2:30 PM" {
+		t.Errorf("unchanged opening hours replaced the text with %q", v.OpeningHoursText)
+	}
+
+	v.SetOpeningHours([]maps.OpeningHoursPeriod{
+		period(time.Monday, "1730", time.Monday, "2300"),
+		period(time.Monday, "1130", time.Monday, "1400"),
+		period(time.Friday, "2200", time.Friday, "0200"),
+		period(time.Saturday, "2200", time.Sunday, "0200"),
+		period(time.Sunday, "0000", time.Monday, "0000"),
+	}, true)
+	want := []string{"Monday: 11:30–14:00, 17:30–23:00", "Tuesday: Closed", "Wednesday: Closed", "Thursday: Closed",
+		"Friday: 22:00–02:00", "Saturday: 22:00–02:00", "Sunday: Open 24 hours"}
+	if !reflect.DeepEqual(v.OpeningHours.WeekdayText, want) || !reflect.DeepEqual(v.OpeningHoursText, want) {
+		t.Errorf("texts = %q and %q, want %q", v.OpeningHours.WeekdayText, v.OpeningHoursText, want)
+	}
+
+	if v.ClosedAllWeek {
+		t.Error("venue with periods is closed all week")
+	}
+
+	v.SetOpeningHours(nil, true)
+	want = []string{"Monday: Closed", "Tuesday: Closed", "Wednesday: Closed", "Thursday: Closed", "Friday: Closed",
+		"Saturday: Closed", "Sunday: Closed"}
+	if !v.ClosedAllWeek || v.OpeningHours.Periods != nil || !reflect.DeepEqual(v.OpeningHoursText, want) {
+		t.Errorf("closed all week: %v, periods %+v and texts %q, want no periods and %q", v.ClosedAllWeek,
+			v.OpeningHours.Periods, v.OpeningHoursText, want)
+	}
+
+	v.SetOpeningHours(nil, false)
+	if v.ClosedAllWeek || v.OpeningHours.WeekdayText != nil || v.OpeningHoursText != nil {
+		t.Errorf("removed opening hours left %+v and %q", v.OpeningHours, v.OpeningHoursText)
+	}
+}
@@ -0,0 +1,66 @@
+package venue
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+//ImportJSONFolder copies every venue of a JSON data folder together with its history and aliases into the given store
+//and gives back how many venues were imported.
+//A missing folder is not an error, there is simply nothing to import. Files that can't be loaded are moved to the
+//quarantine subfolder and fail the import as long as they are there, so no venue is lost without notice
+func ImportJSONFolder(folder string, dst VenueStore) (int, error) {
+	_, err := os.Stat(folder)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	src := NewJSONStore(folder)
+	vv, _, err := src.ListWithProblems()
+	if err != nil {
+		return 0, errors.New("Error reading JSON folder: " + err.Error())
+	}
+	//files quarantined earlier count aswell, they are venues the user would lose
+	problems := src.Problems()
+	if len(problems) > 0 {
+		var files []string
+		for _, p := range problems {
+			files = append(files, p.File+" ("+p.Error+")")
+		}
+		return 0, errors.New("Error loading venues, fix or remove them from the quarantine folder first: " +
+			strings.Join(files, ", "))
+	}
+	imported := make(map[string]bool)
+	for i, v := range vv {
+		err = dst.Put(v)
+		if err != nil {
+			return i, errors.New("Error importing venue " + v.VenueID + ": " + err.Error())
+		}
+		imported[v.VenueID] = true
+		history, err := src.History(v.VenueID)
+		if err != nil {
+			return i, errors.New("Error reading history of venue " + v.VenueID + ": " + err.Error())
+		}
+		if len(history) > 0 {
+			err = dst.AddHistory(v.VenueID, history)
+			if err != nil {
+				return i, errors.New("Error importing history of venue " + v.VenueID + ": " + err.Error())
+			}
+		}
+	}
+	aliases, err := src.Aliases()
+	if err != nil {
+		return len(vv), errors.New("Error reading aliases: " + err.Error())
+	}
+	for alias, id := range aliases {
+		//aliases of venues deleted earlier lead nowhere
+		if !imported[id] {
+			continue
+		}
+		err = dst.PutAlias(alias, id)
+		if err != nil {
+			return len(vv), errors.New("Error importing alias " + alias + ": " + err.Error())
+		}
+	}
+	return len(vv), nil
+}
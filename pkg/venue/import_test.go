@@ -0,0 +1,110 @@
+package venue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImportJSONFolderFailsOnBrokenFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folder := filepath.Join(dir, "data")
+	src := NewJSONStore(folder)
+	err = os.Mkdir(folder, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.Put(Venue{VenueID: "roma", Name: "Pizza Roma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(folder, "broken.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewJSONStore(filepath.Join(dir, "dst"))
+	err = os.Mkdir(filepath.Join(dir, "dst"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := ImportJSONFolder(folder, dst)
+	if err == nil {
+		t.Fatalf("ImportJSONFolder imported %d venues despite a broken file", n)
+	}
+	if _, err := os.Stat(filepath.Join(folder, quarantineFolder, "broken.json")); err != nil {
+		t.Errorf("broken file was not quarantined: %v", err)
+	}
+
+	_, err = ImportJSONFolder(folder, dst)
+	if err == nil {
+		t.Fatal("ImportJSONFolder succeeded while the broken file is in quarantine")
+	}
+
+	//once the broken file is out of the way the import goes through
+	err = os.Remove(filepath.Join(folder, quarantineFolder, "broken.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err = ImportJSONFolder(folder, dst)
+	if err != nil || n != 1 {
+		t.Fatalf("ImportJSONFolder() = %d, %v, want 1 venue", n, err)
+	}
+}
+
+func TestImportJSONFolderCopiesHistoryAndAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folder := filepath.Join(dir, "data")
+	err = os.Mkdir(folder, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewJSONStore(folder)
+	err = src.Put(Venue{VenueID: "roma", Name: "Pizza Roma"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	change := Change{Field: "PhoneNumber", Old: "1", New: "2", Source: "refresh"}
+	err = src.AddHistory("roma", []Change{change})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.PutAlias("Pizza Roma", "roma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = src.PutAlias("gone", "deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := NewSQLiteStore(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	n, err := ImportJSONFolder(folder, dst)
+	if err != nil || n != 1 {
+		t.Fatalf("ImportJSONFolder() = %d, %v, want 1 venue", n, err)
+	}
+	v, err := dst.Get("Pizza Roma")
+	if err != nil || v.VenueID != "roma" {
+		t.Errorf("Get by alias = %q, %v, want venue roma", v.VenueID, err)
+	}
+	history, err := dst.History("roma")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Field != change.Field || history[0].New != change.New {
+		t.Errorf("History() = %+v, want %+v", history, change)
+	}
+}
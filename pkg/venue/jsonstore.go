@@ -0,0 +1,527 @@
+package venue
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//quarantineFolder is the subfolder of the data folder undecodable venue files are moved to
+const quarantineFolder = "quarantine"
+
+//metaFolder is the subfolder of the data folder holding everything that is not a venue
+const metaFolder = "meta"
+
+const aliasFile = "aliases.json"
+
+//historyFolder is the folder inside the meta folder holding a history file per venue
+const historyFolder = "history"
+
+//JSONStore is a VenueStore saving every venue as its own JSON file inside a folder.
+//Files are replaced atomically and all writes to the same venue are serialized
+type JSONStore struct {
+	folder string
+
+	mutex    sync.Mutex
+	locks    map[string]*venueLock
+	problems map[string]StoreProblem
+
+	aliasmutex sync.Mutex
+	aliases    map[string]string
+}
+
+type venueLock struct {
+	sync.Mutex
+	refs int
+}
+
+//NewJSONStore gives back a JSONStore working on the given folder
+func NewJSONStore(folder string) *JSONStore {
+	return &JSONStore{folder: folder, locks: make(map[string]*venueLock), problems: make(map[string]StoreProblem)}
+}
+
+//lock blocks until the venue with the given ID is held exclusively and gives back the function to release it
+func (s *JSONStore) lock(id string) func() {
+	s.mutex.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &venueLock{}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mutex.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.mutex.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, id)
+		}
+		s.mutex.Unlock()
+	}
+}
+
+//validID tells if the ID can be used as file name inside the folder. IDs leaving the folder like ../config
+//are never found
+func validID(id string) bool {
+	return id != "" && !strings.Contains(id, "..") && !strings.ContainsAny(id, "/\\"+string(os.PathSeparator))
+}
+
+func (s *JSONStore) getJSONFile(id string) string {
+	return filepath.Join(s.folder, id) + ".json"
+}
+
+func (s *JSONStore) getHistoryFile(id string) string {
+	return filepath.Join(s.folder, metaFolder, historyFolder, id) + ".json"
+}
+
+func (s *JSONStore) getAliasFile() string {
+	return filepath.Join(s.folder, metaFolder, aliasFile)
+}
+
+//loadAliases reads the alias file on first use, aliasmutex has to be held
+func (s *JSONStore) loadAliases() error {
+	if s.aliases != nil {
+		return nil
+	}
+	aliases := make(map[string]string)
+	file, err := os.Open(s.getAliasFile())
+	if os.IsNotExist(err) {
+		s.aliases = aliases
+		return nil
+	}
+	if err != nil {
+		return errors.New("Error opening alias file: " + err.Error())
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&aliases)
+	if err != nil {
+		return errors.New("Error decoding alias file: " + err.Error())
+	}
+	s.aliases = aliases
+	return nil
+}
+
+//saveAliases writes the alias file, aliasmutex has to be held
+func (s *JSONStore) saveAliases() error {
+	err := os.MkdirAll(filepath.Join(s.folder, metaFolder), 0755)
+	if err != nil {
+		return errors.New("Error creating meta folder: " + err.Error())
+	}
+	return writeFileAtomic(s.getAliasFile(), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(s.aliases)
+	})
+}
+
+//resolve gives back the ID of the venue the given ID or alias belongs to
+func (s *JSONStore) resolve(id string) string {
+	if !validID(id) {
+		return id
+	}
+	if _, err := os.Stat(s.getJSONFile(id)); err == nil {
+		return id
+	}
+	s.aliasmutex.Lock()
+	defer s.aliasmutex.Unlock()
+	if s.loadAliases() != nil {
+		return id
+	}
+	if target, ok := s.aliases[id]; ok {
+		return target
+	}
+	return id
+}
+
+//PutAlias saves the alias for the venue in the alias file of the meta folder
+func (s *JSONStore) PutAlias(alias string, id string) error {
+	s.aliasmutex.Lock()
+	defer s.aliasmutex.Unlock()
+	err := s.loadAliases()
+	if err != nil {
+		return err
+	}
+	s.aliases[alias] = id
+	return s.saveAliases()
+}
+
+//Aliases gives back every alias of the alias file together with the ID of its venue
+func (s *JSONStore) Aliases() (map[string]string, error) {
+	s.aliasmutex.Lock()
+	defer s.aliasmutex.Unlock()
+	err := s.loadAliases()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]string, len(s.aliases))
+	for alias, id := range s.aliases {
+		res[alias] = id
+	}
+	return res, nil
+}
+
+//removeAliases drops all aliases pointing to the venue with the given ID
+func (s *JSONStore) removeAliases(id string) error {
+	s.aliasmutex.Lock()
+	defer s.aliasmutex.Unlock()
+	err := s.loadAliases()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for alias, target := range s.aliases {
+		if target == id {
+			delete(s.aliases, alias)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.saveAliases()
+}
+
+//Get loads the venue with the given ID from its JSON File
+func (s *JSONStore) Get(id string) (Venue, error) {
+	var v Venue
+	if !validID(id) {
+		return v, ErrVenueNotFound
+	}
+	filename := s.getJSONFile(s.resolve(id))
+	_, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return v, ErrVenueNotFound
+	}
+	err = v.loadfromFile(filename)
+	return v, err
+}
+
+//Put saves the venue to its JSON File
+func (s *JSONStore) Put(v Venue) error {
+	if v.VenueID == "" {
+		return errors.New("Venue has no ID")
+	}
+	if !validID(v.VenueID) {
+		return errors.New("Invalid venue ID " + v.VenueID)
+	}
+	unlock := s.lock(v.VenueID)
+	defer unlock()
+	return v.savetoFile(s.getJSONFile(v.VenueID))
+}
+
+//Update loads the venue, applies fn and saves it again while no one else can write the venue
+func (s *JSONStore) Update(id string, fn func(v *Venue) error) (Venue, error) {
+	if !validID(id) {
+		return Venue{}, ErrVenueNotFound
+	}
+	id = s.resolve(id)
+	unlock := s.lock(id)
+	defer unlock()
+	v, err := s.Get(id)
+	if err != nil {
+		return v, err
+	}
+	err = fn(&v)
+	if err != nil {
+		return v, err
+	}
+	if v.VenueID != id {
+		return v, ErrVenueIDChanged
+	}
+	v.Revision++
+	return v, v.savetoFile(s.getJSONFile(id))
+}
+
+//MarkRefreshed sets the LastRefresh of the venue and saves it with the same Revision
+func (s *JSONStore) MarkRefreshed(id string, at time.Time) error {
+	if !validID(id) {
+		return ErrVenueNotFound
+	}
+	id = s.resolve(id)
+	unlock := s.lock(id)
+	defer unlock()
+	v, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	v.LastRefresh = at
+	return v.savetoFile(s.getJSONFile(id))
+}
+
+//AddHistory appends the changes to the history file of the venue
+func (s *JSONStore) AddHistory(id string, changes []Change) error {
+	if !validID(id) {
+		return ErrVenueNotFound
+	}
+	id = s.resolve(id)
+	unlock := s.lock(id)
+	defer unlock()
+	history, err := s.loadHistory(id)
+	if err != nil {
+		return err
+	}
+	history = append(history, changes...)
+	err = os.MkdirAll(filepath.Join(s.folder, metaFolder, historyFolder), 0755)
+	if err != nil {
+		return errors.New("Error creating history folder: " + err.Error())
+	}
+	return writeFileAtomic(s.getHistoryFile(id), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(history)
+	})
+}
+
+//History gives back the changes saved in the history file of the venue
+func (s *JSONStore) History(id string) ([]Change, error) {
+	return s.loadHistory(s.resolve(id))
+}
+
+//loadHistory reads the history file, a venue without one has no history yet
+func (s *JSONStore) loadHistory(id string) ([]Change, error) {
+	if !validID(id) {
+		return nil, ErrVenueNotFound
+	}
+	_, err := os.Stat(s.getJSONFile(id))
+	if os.IsNotExist(err) {
+		return nil, ErrVenueNotFound
+	}
+	history := []Change{}
+	file, err := os.Open(s.getHistoryFile(id))
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, errors.New("Error opening history file: " + err.Error())
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&history)
+	if err != nil {
+		return nil, errors.New("Error decoding history file: " + err.Error())
+	}
+	return history, nil
+}
+
+//Delete removes the JSON File of the venue from the drive
+func (s *JSONStore) Delete(id string, check func(v Venue) error) error {
+	if !validID(id) {
+		return ErrVenueNotFound
+	}
+	id = s.resolve(id)
+	unlock := s.lock(id)
+	defer unlock()
+	if check != nil {
+		v, err := s.Get(id)
+		if err != nil {
+			return err
+		}
+		err = check(v)
+		if err != nil {
+			return err
+		}
+	}
+	err := os.Remove(s.getJSONFile(id))
+	if os.IsNotExist(err) {
+		return ErrVenueNotFound
+	}
+	if err != nil {
+		return errors.New("Error deleting file: " + err.Error())
+	}
+	syncDir(s.folder)
+	err = os.Remove(s.getHistoryFile(id))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.New("Error deleting history: " + err.Error())
+	}
+	err = s.removeAliases(id)
+	if err != nil {
+		return errors.New("Error removing aliases: " + err.Error())
+	}
+	return nil
+}
+
+//List gives back a slice with all venues in the folder. Files that can't be loaded are skipped,
+//their problems can be fetched with Problems afterwards
+func (s *JSONStore) List() ([]Venue, error) {
+	result, _, err := s.ListWithProblems()
+	return result, err
+}
+
+//ListWithProblems gives back all venues in the folder together with a problem for every file that could not be loaded.
+//Those files are moved into the quarantine subfolder so they don't show up again
+func (s *JSONStore) ListWithProblems() ([]Venue, []StoreProblem, error) {
+	var result []Venue
+	var problems []StoreProblem
+	files, err := ioutil.ReadDir(s.folder)
+	if err != nil {
+		return result, problems, errors.New("Error reading folder: " + err.Error())
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		extension := filepath.Ext(f.Name())
+		if strings.Compare(extension, ".json") != 0 {
+			continue
+		}
+		var v Venue
+		err := v.loadfromFile(filepath.Join(s.folder, f.Name()))
+		if err != nil {
+			p, ok := s.quarantine(f.Name())
+			if ok {
+				problems = append(problems, p)
+			}
+			continue
+		}
+		result = append(result, v)
+	}
+	return result, problems, nil
+}
+
+//quarantine moves a file that couldn't be loaded out of the way and records the problem.
+//It gives back false if the file turned out to be fine or is gone once the venue is held exclusively
+func (s *JSONStore) quarantine(name string) (StoreProblem, bool) {
+	unlock := s.lock(strings.TrimSuffix(name, ".json"))
+	defer unlock()
+
+	p := StoreProblem{File: name, Time: time.Now()}
+	filename := filepath.Join(s.folder, name)
+	var v Venue
+	err := v.loadfromFile(filename)
+	if err == nil {
+		return p, false
+	}
+	if _, staterr := os.Stat(filename); os.IsNotExist(staterr) {
+		return p, false
+	}
+	p.Error = err.Error()
+
+	dir := filepath.Join(s.folder, quarantineFolder)
+	err = os.MkdirAll(dir, 0755)
+	if err == nil {
+		target := filepath.Join(dir, name)
+		if _, staterr := os.Stat(target); staterr == nil {
+			target = filepath.Join(dir, name+"."+p.Time.Format("20060102150405"))
+		}
+		err = os.Rename(filename, target)
+		if err == nil {
+			p.Quarantined = target
+		}
+	}
+	if err != nil {
+		p.Error = p.Error + " (could not quarantine file: " + err.Error() + ")"
+	}
+
+	s.mutex.Lock()
+	s.problems[name] = p
+	s.mutex.Unlock()
+	return p, true
+}
+
+//Problems gives back the files found broken since the start of the program aswell as
+//all files that were moved to the quarantine folder earlier
+func (s *JSONStore) Problems() []StoreProblem {
+	var result []StoreProblem
+	known := make(map[string]bool)
+	s.mutex.Lock()
+	for _, p := range s.problems {
+		result = append(result, p)
+		known[p.Quarantined] = true
+	}
+	s.mutex.Unlock()
+
+	dir := filepath.Join(s.folder, quarantineFolder)
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return result
+	}
+	for _, f := range files {
+		target := filepath.Join(dir, f.Name())
+		if f.IsDir() || known[target] {
+			continue
+		}
+		result = append(result, StoreProblem{File: f.Name(), Error: "Quarantined earlier", Quarantined: target, Time: f.ModTime()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].File < result[j].File })
+	return result
+}
+
+//Query gives back all venues in the folder matching the query
+func (s *JSONStore) Query(q Query) ([]Venue, error) {
+	vv, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return filterVenues(vv, q), nil
+}
+
+//savetoFile save a venue to a json File
+func (v *Venue) savetoFile(filename string) error {
+	v.AssignVisitIDs()
+	return writeFileAtomic(filename, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(v)
+	})
+}
+
+//writeFileAtomic hands a temporary file in the same folder to write, which is synced and then renamed
+//over the old file, so readers never see a half written file
+func writeFileAtomic(filename string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(filename)
+	file, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return errors.New("Error creating file: " + err.Error())
+	}
+	tmpname := file.Name()
+	defer os.Remove(tmpname)
+	err = write(file)
+	if err != nil {
+		file.Close()
+		return errors.New("Error saving file: " + err.Error())
+	}
+	err = file.Sync()
+	if err != nil {
+		file.Close()
+		return errors.New("Error syncing file: " + err.Error())
+	}
+	err = file.Close()
+	if err != nil {
+		return errors.New("Error closing file: " + err.Error())
+	}
+	err = os.Rename(tmpname, filename)
+	if err != nil {
+		return errors.New("Error replacing file: " + err.Error())
+	}
+	syncDir(dir)
+	return nil
+}
+
+//syncDir makes the rename of a file durable. Not every OS allows to sync a folder, so errors are ignored
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+//loadfromFile Loads a venue from a json File
+func (v *Venue) loadfromFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return errors.New("Error opening file: " + err.Error())
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(v)
+	if err != nil {
+		return errors.New("Error decoding file: " + err.Error())
+	}
+	return nil
+}
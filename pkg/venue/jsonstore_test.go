@@ -0,0 +1,45 @@
+package venue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONStoreRejectsIDsLeavingFolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folder := filepath.Join(dir, "data")
+	err = os.Mkdir(folder, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(outside, []byte(`{"VenueID":"config","Name":"Config"}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewJSONStore(folder)
+	for _, id := range []string{"../config", "..", "a/b", `a\b`, ""} {
+		if _, err := s.Get(id); err != ErrVenueNotFound {
+			t.Errorf("Get(%q) = %v, want ErrVenueNotFound", id, err)
+		}
+		if err := s.Delete(id, nil); err != ErrVenueNotFound {
+			t.Errorf("Delete(%q) = %v, want ErrVenueNotFound", id, err)
+		}
+		if _, err := s.History(id); err != ErrVenueNotFound {
+			t.Errorf("History(%q) = %v, want ErrVenueNotFound", id, err)
+		}
+	}
+	if err := s.Put(Venue{VenueID: "../config", Name: "Config"}); err == nil {
+		t.Error("Put with ID ../config succeeded")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("file outside the folder is gone: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package venue
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+//legacyIDNamespace is used to derive the new ID of a venue from its legacy ID, so an interrupted migration
+//ends up with the same IDs when it is run again
+var legacyIDNamespace = uuid.MustParse("4f0c7a52-6f2e-4c59-9d0e-1b7d3c1e8a61")
+
+//IsLegacyVenueID checks if the ID was built the old way from the SHA1 of Name and Address
+func IsLegacyVenueID(id string) bool {
+	b, err := base64.URLEncoding.DecodeString(id)
+	return err == nil && len(b) == sha1.Size
+}
+
+//MigrateLegacyIDs gives every venue that still has a legacy ID a new stable one. The old ID is kept as an alias,
+//so bookmarks using it still work. It gives back how many venues were migrated
+func MigrateLegacyIDs(s VenueStore) (int, error) {
+	vv, err := s.List()
+	if err != nil {
+		return 0, errors.New("Error listing venues: " + err.Error())
+	}
+	count := 0
+	for _, v := range vv {
+		if !IsLegacyVenueID(v.VenueID) {
+			continue
+		}
+		legacyid := v.VenueID
+		v.VenueID = uuid.NewSHA1(legacyIDNamespace, []byte(legacyid)).String()
+		err = s.Put(v)
+		if err != nil {
+			return count, errors.New("Error saving migrated venue " + legacyid + ": " + err.Error())
+		}
+		err = s.PutAlias(legacyid, v.VenueID)
+		if err != nil {
+			return count, errors.New("Error saving alias for venue " + legacyid + ": " + err.Error())
+		}
+		err = s.Delete(legacyid, nil)
+		if err != nil && err != ErrVenueNotFound {
+			return count, errors.New("Error deleting legacy venue " + legacyid + ": " + err.Error())
+		}
+		count++
+	}
+	return count, nil
+}
+
+//MigrateVisitIDs gives the visits saved before visits had IDs one. It gives back how many venues were changed
+func MigrateVisitIDs(s VenueStore) (int, error) {
+	vv, err := s.List()
+	if err != nil {
+		return 0, errors.New("Error listing venues: " + err.Error())
+	}
+	count := 0
+	for _, v := range vv {
+		if !v.AssignVisitIDs() {
+			continue
+		}
+		err = s.Put(v)
+		if err != nil {
+			return count, errors.New("Error saving visit IDs of venue " + v.VenueID + ": " + err.Error())
+		}
+		count++
+	}
+	return count, nil
+}
@@ -0,0 +1,153 @@
+package venue
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//OSMProviderName is the PlaceProvider of venues found in OpenStreetMap
+const OSMProviderName = "osm"
+
+//DefaultNominatimURL is the public Nominatim instance of OpenStreetMap
+const DefaultNominatimURL = "https://nominatim.openstreetmap.org"
+
+//OSMProvider looks up places in OpenStreetMap with the Nominatim API. The PlaceID is the OSM type
+//(N for node, W for way, R for relation) followed by the OSM ID, e.g. N240109189
+type OSMProvider struct {
+	baseurl   string
+	useragent string
+	client    *http.Client
+}
+
+//nominatimPlace is one result of the search and lookup endpoints of Nominatim in the jsonv2 format
+type nominatimPlace struct {
+	OSMType     string            `json:"osm_type"`
+	OSMID       int64             `json:"osm_id"`
+	Name        string            `json:"name"`
+	DisplayName string            `json:"display_name"`
+	ExtraTags   map[string]string `json:"extratags"`
+}
+
+//NewOSMProvider sets up a provider for the Nominatim instance at baseurl, which defaults to DefaultNominatimURL.
+//Nominatim requires every application to identify itself with its own useragent
+func NewOSMProvider(baseurl string, useragent string) (*OSMProvider, error) {
+	if baseurl == "" {
+		baseurl = DefaultNominatimURL
+	}
+	if useragent == "" {
+		return nil, errors.New("A user agent is required by Nominatim")
+	}
+	return &OSMProvider{baseurl: strings.TrimSuffix(baseurl, "/"), useragent: useragent,
+		client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+//Name gives back OSMProviderName
+func (o *OSMProvider) Name() string {
+	return OSMProviderName
+}
+
+//Search queries the search endpoint of Nominatim
+func (o *OSMProvider) Search(query string) ([]PlaceCandidate, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", strconv.Itoa(maxCandidates))
+	pp, err := o.get("/search", params)
+	if err != nil {
+		return nil, err
+	}
+	var res []PlaceCandidate
+	for _, p := range pp {
+		id := osmPlaceID(p)
+		if id == "" {
+			continue
+		}
+		name := p.Name
+		if name == "" {
+			name = strings.Split(p.DisplayName, ",")[0]
+		}
+		res = append(res, PlaceCandidate{PlaceID: id, Name: name, Address: p.DisplayName})
+	}
+	return res, nil
+}
+
+//Details queries the lookup endpoint of Nominatim and parses the opening_hours tag of the place
+func (o *OSMProvider) Details(placeid string) (PlaceDetails, error) {
+	if len(placeid) < 2 || !strings.Contains("NWR", placeid[:1]) {
+		return PlaceDetails{}, errors.New("Invalid OSM place ID: " + placeid)
+	}
+	params := url.Values{}
+	params.Set("osm_ids", placeid)
+	pp, err := o.get("/lookup", params)
+	if err != nil {
+		return PlaceDetails{}, err
+	}
+	if len(pp) < 1 {
+		return PlaceDetails{}, errors.New("Place " + placeid + " not found")
+	}
+	tags := pp[0].ExtraTags
+	res := PlaceDetails{Website: firstTag(tags, "website", "contact:website"),
+		PhoneNumber: firstTag(tags, "phone", "contact:phone"), BusinessStatus: BusinessOperational}
+	//closed shops are kept in OpenStreetMap with their tags prefixed by disused: or was:
+	for k := range tags {
+		if strings.HasPrefix(k, "disused:") || strings.HasPrefix(k, "was:") {
+			res.BusinessStatus = BusinessClosedPermanently
+		}
+	}
+	hours := tags["opening_hours"]
+	if hours != "" {
+		oh, err := ParseOSMOpeningHours(hours)
+		if err != nil {
+			//the tag is written by hand and often uses more than we understand, keep it readable at least
+			res.OpeningHoursText = []string{hours}
+		} else {
+			res.OpeningHours = oh
+			res.OpeningHoursText = oh.WeekdayText
+		}
+	}
+	return res, nil
+}
+
+func (o *OSMProvider) get(endpoint string, params url.Values) ([]nominatimPlace, error) {
+	params.Set("format", "jsonv2")
+	params.Set("extratags", "1")
+	req, err := http.NewRequest("GET", o.baseurl+endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", o.useragent)
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("Nominatim answered with " + resp.Status)
+	}
+	var pp []nominatimPlace
+	err = json.NewDecoder(resp.Body).Decode(&pp)
+	if err != nil {
+		return nil, errors.New("Error decoding Nominatim response: " + err.Error())
+	}
+	return pp, nil
+}
+
+func osmPlaceID(p nominatimPlace) string {
+	if p.OSMType == "" || p.OSMID == 0 {
+		return ""
+	}
+	return strings.ToUpper(p.OSMType[:1]) + strconv.FormatInt(p.OSMID, 10)
+}
+
+func firstTag(tags map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if tags[k] != "" {
+			return tags[k]
+		}
+	}
+	return ""
+}
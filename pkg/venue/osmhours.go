@@ -0,0 +1,210 @@
+package venue
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+var osmWeekdays = map[string]time.Weekday{"Mo": time.Monday, "Tu": time.Tuesday, "We": time.Wednesday,
+	"Th": time.Thursday, "Fr": time.Friday, "Sa": time.Saturday, "Su": time.Sunday}
+
+//textWeekdays is the order of the days in the WeekdayText, like the Places API does it
+var textWeekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+	time.Saturday, time.Sunday}
+
+//osmSpan is an opening time in minutes since midnight of its day, the end can be after midnight
+type osmSpan struct {
+	start int
+	end   int
+}
+
+//ParseOSMOpeningHours converts an opening_hours tag of OpenStreetMap into the opening hours of a venue.
+//Rules with weekdays, times, off and 24/7 are understood, like "Mo-Fr 11:30-14:00,17:00-23:00; Sa 17:00-01:00; Su off".
+//Later rules replace the earlier ones for their days, rules separated by ", " add to them.
+//Rules for public and school holidays are skipped, rules for months or weeks give back an error
+func ParseOSMOpeningHours(s string) (maps.OpeningHours, error) {
+	var res maps.OpeningHours
+	var week [7][]osmSpan
+	s = strings.TrimSpace(s)
+	if s == "24/7" {
+		for d := range week {
+			week[d] = []osmSpan{{0, 24 * 60}}
+		}
+		//the Places API describes a venue which is always open with one period without closing time
+		res.Periods = []maps.OpeningHoursPeriod{{Open: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0000"}}}
+		res.WeekdayText = osmWeekdayText(week)
+		return res, nil
+	}
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		//there is nowhere to keep opening hours of holidays, the regular ones are still useful without them
+		if rule == "" || strings.HasPrefix(rule, "PH") || strings.HasPrefix(rule, "SH") {
+			continue
+		}
+		for i, part := range splitOSMAdditionalRules(rule) {
+			days, spans, err := parseOSMRule(part)
+			if err != nil {
+				return res, err
+			}
+			for _, d := range days {
+				if i == 0 {
+					week[d] = nil
+				}
+				week[d] = append(week[d], spans...)
+			}
+		}
+	}
+	for d, spans := range week {
+		sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+		for _, sp := range spans {
+			res.Periods = append(res.Periods, maps.OpeningHoursPeriod{
+				Open:  maps.OpeningHoursOpenClose{Day: time.Weekday(d), Time: osmClock(sp.start, "")},
+				Close: maps.OpeningHoursOpenClose{Day: time.Weekday((d + sp.end/(24*60)) % 7), Time: osmClock(sp.end, "")},
+			})
+		}
+	}
+	res.WeekdayText = osmWeekdayText(week)
+	return res, nil
+}
+
+//splitOSMAdditionalRules splits a rule at every ", " which is followed by a new weekday selector.
+//Empty rules like in "Mo 10:00-12:00, , Tu 10:00-12:00" are dropped
+func splitOSMAdditionalRules(rule string) []string {
+	var res []string
+	current := ""
+	for _, p := range strings.Split(rule, ", ") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, ok := parseOSMWeekdays(strings.Fields(p)[0]); ok && current != "" {
+			res = append(res, current)
+			current = p
+			continue
+		}
+		if current != "" {
+			p = current + "," + p
+		}
+		current = p
+	}
+	if current != "" {
+		res = append(res, current)
+	}
+	return res
+}
+
+func parseOSMRule(rule string) ([]time.Weekday, []osmSpan, error) {
+	fields := strings.Fields(rule)
+	days, ok := parseOSMWeekdays(fields[0])
+	if ok {
+		fields = fields[1:]
+	} else {
+		days = []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday,
+			time.Friday, time.Saturday}
+	}
+	selector := strings.Join(fields, "")
+	switch selector {
+	case "":
+		return days, []osmSpan{{0, 24 * 60}}, nil
+	case "off", "closed":
+		return days, nil, nil
+	}
+	var spans []osmSpan
+	for _, t := range strings.Split(selector, ",") {
+		parts := strings.Split(t, "-")
+		if len(parts) != 2 {
+			return nil, nil, errors.New("Unsupported opening_hours rule: " + rule)
+		}
+		start, err := parseOSMClock(parts[0])
+		if err != nil {
+			return nil, nil, errors.New("Unsupported opening_hours rule: " + rule)
+		}
+		end, err := parseOSMClock(parts[1])
+		if err != nil {
+			return nil, nil, errors.New("Unsupported opening_hours rule: " + rule)
+		}
+		if end <= start {
+			end += 24 * 60
+		}
+		spans = append(spans, osmSpan{start, end})
+	}
+	return days, spans, nil
+}
+
+//parseOSMWeekdays reads a weekday selector like "Mo-Fr,Su"
+func parseOSMWeekdays(selector string) ([]time.Weekday, bool) {
+	var res []time.Weekday
+	for _, part := range strings.Split(selector, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return nil, false
+		}
+		from, ok := osmWeekdays[bounds[0]]
+		if !ok {
+			return nil, false
+		}
+		to := from
+		if len(bounds) == 2 {
+			to, ok = osmWeekdays[bounds[1]]
+			if !ok {
+				return nil, false
+			}
+		}
+		//ranges like Fr-Mo go over the weekend
+		for d := from; ; d = (d + 1) % 7 {
+			res = append(res, d)
+			if d == to {
+				break
+			}
+		}
+	}
+	return res, true
+}
+
+//parseOSMClock reads a time like 17:30 as minutes since midnight, times after 24:00 belong to the next day
+func parseOSMClock(s string) (int, error) {
+	if len(s) != 5 || s[2] != ':' {
+		return 0, errors.New("Invalid time " + s)
+	}
+	hours, err := strconv.Atoi(s[:2])
+	if err != nil {
+		return 0, errors.New("Invalid time " + s)
+	}
+	minutes, err := strconv.Atoi(s[3:])
+	if err != nil || hours > 48 || minutes > 59 {
+		return 0, errors.New("Invalid time " + s)
+	}
+	return hours*60 + minutes, nil
+}
+
+//osmClock formats minutes since midnight as time of the day, separated by sep
+func osmClock(minutes int, sep string) string {
+	minutes = minutes % (24 * 60)
+	return fmt.Sprintf("%02d%s%02d", minutes/60, sep, minutes%60)
+}
+
+func osmWeekdayText(week [7][]osmSpan) []string {
+	var res []string
+	for _, d := range textWeekdays {
+		spans := week[d]
+		var times []string
+		for _, sp := range spans {
+			if sp.start == 0 && sp.end == 24*60 {
+				times = append(times, "Open 24 hours")
+				continue
+			}
+			times = append(times, osmClock(sp.start, ":")+"–"+osmClock(sp.end, ":"))
+		}
+		if len(times) == 0 {
+			times = []string{"Closed"}
+		}
+		res = append(res, d.String()+": "+strings.Join(times, ", "))
+	}
+	return res
+}
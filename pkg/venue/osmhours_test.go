@@ -0,0 +1,74 @@
+package venue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+func TestParseOSMOpeningHours(t *testing.T) {
+	closed := "Closed"
+	tests := []struct {
+		name string
+		in   string
+		text []string
+	}{
+		{"weekdays with two spans", "Mo-Fr 11:30-14:00,17:00-23:00; Sa 17:00-01:00; Su off", []string{
+			"Monday: 11:30–14:00, 17:00–23:00", "Tuesday: 11:30–14:00, 17:00–23:00",
+			"Wednesday: 11:30–14:00, 17:00–23:00", "Thursday: 11:30–14:00, 17:00–23:00",
+			"Friday: 11:30–14:00, 17:00–23:00", "Saturday: 17:00–01:00", "Sunday: " + closed}},
+		{"always open", "24/7", []string{
+			"Monday: Open 24 hours", "Tuesday: Open 24 hours", "Wednesday: Open 24 hours",
+			"Thursday: Open 24 hours", "Friday: Open 24 hours", "Saturday: Open 24 hours", "Sunday: Open 24 hours"}},
+		{"empty additional rule", "Mo 10:00-12:00, , Tu 10:00-12:00", []string{
+			"Monday: 10:00–12:00", "Tuesday: 10:00–12:00", "Wednesday: " + closed, "Thursday: " + closed,
+			"Friday: " + closed, "Saturday: " + closed, "Sunday: " + closed}},
+		{"additional rule for the same day", "Mo 10:00-12:00, Mo 14:00-16:00", []string{
+			"Monday: 10:00–12:00, 14:00–16:00", "Tuesday: " + closed, "Wednesday: " + closed,
+			"Thursday: " + closed, "Friday: " + closed, "Saturday: " + closed, "Sunday: " + closed}},
+		{"later rule replaces earlier one", "Mo-Su 10:00-20:00; We off; PH off", []string{
+			"Monday: 10:00–20:00", "Tuesday: 10:00–20:00", "Wednesday: " + closed, "Thursday: 10:00–20:00",
+			"Friday: 10:00–20:00", "Saturday: 10:00–20:00", "Sunday: 10:00–20:00"}},
+		{"range over the weekend", "Fr-Mo 18:00-22:00;", []string{
+			"Monday: 18:00–22:00", "Tuesday: " + closed, "Wednesday: " + closed, "Thursday: " + closed,
+			"Friday: 18:00–22:00", "Saturday: 18:00–22:00", "Sunday: 18:00–22:00"}},
+		{"times without weekdays", "11:00-15:00", []string{
+			"Monday: 11:00–15:00", "Tuesday: 11:00–15:00", "Wednesday: 11:00–15:00", "Thursday: 11:00–15:00",
+			"Friday: 11:00–15:00", "Saturday: 11:00–15:00", "Sunday: 11:00–15:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ParseOSMOpeningHours(tt.in)
+			if err != nil {
+				t.Fatalf("ParseOSMOpeningHours(%q) failed: %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(res.WeekdayText, tt.text) {
+				t.Errorf("ParseOSMOpeningHours(%q).WeekdayText = %q, want %q", tt.in, res.WeekdayText, tt.text)
+			}
+		})
+	}
+}
+
+func TestParseOSMOpeningHoursOvernightPeriod(t *testing.T) {
+	res, err := ParseOSMOpeningHours("Sa 17:00-01:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []maps.OpeningHoursPeriod{{
+		Open:  maps.OpeningHoursOpenClose{Day: time.Saturday, Time: "1700"},
+		Close: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0100"},
+	}}
+	if !reflect.DeepEqual(res.Periods, want) {
+		t.Errorf("Periods = %+v, want %+v", res.Periods, want)
+	}
+}
+
+func TestParseOSMOpeningHoursErrors(t *testing.T) {
+	for _, in := range []string{"Jan-Mar 10:00-12:00", "Mo 10:00", "Mo 10-12", "Mo 10:00-25:61", "week 1-20 Mo 10:00-12:00"} {
+		if _, err := ParseOSMOpeningHours(in); err == nil {
+			t.Errorf("ParseOSMOpeningHours(%q) succeeded, want an error", in)
+		}
+	}
+}
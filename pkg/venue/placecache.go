@@ -0,0 +1,186 @@
+package venue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrNoPlaceCache is returned if the statistics of the cache are requested but the PlaceProvider is not cached
+var ErrNoPlaceCache = errors.New("Place provider is not cached")
+
+//PlaceCost is the estimated price of a single request to a PlaceProvider
+type PlaceCost struct {
+	Search  float64
+	Details float64
+}
+
+//GoogleCost is the list price in USD of a text search and of a details request with contact data
+var GoogleCost = PlaceCost{Search: 0.032, Details: 0.020}
+
+//PlaceCacheStats counts how often the cache answered a request since the start. EstimatedCost is the price of the
+//requests sent to the provider, EstimatedSavings the price of the ones answered from the cache.
+//A response which could not be saved in the cache is still given back, the last such error is kept in SaveError
+type PlaceCacheStats struct {
+	TTL              string
+	Since            time.Time
+	SearchHits       int
+	SearchMisses     int
+	DetailsHits      int
+	DetailsMisses    int
+	EstimatedCost    float64
+	EstimatedSavings float64
+	SaveErrors       int
+	SaveError        string `json:",omitempty"`
+}
+
+//placeCacheEntry is the content of a cache file, Key is kept to tell hash collisions apart
+type placeCacheEntry struct {
+	Key      string
+	Fetched  time.Time
+	Response json.RawMessage
+}
+
+//CachedProvider saves the responses of a PlaceProvider as JSON files in a folder and answers requests from there
+//until they are older than the TTL. A TTL of zero disables the cache, the requests are still counted
+type CachedProvider struct {
+	provider PlaceProvider
+	folder   string
+	ttl      time.Duration
+	cost     PlaceCost
+	mutex    sync.Mutex
+	stats    PlaceCacheStats
+}
+
+//NewCachedProvider wraps the provider with a cache inside folder
+func NewCachedProvider(p PlaceProvider, folder string, ttl time.Duration, cost PlaceCost) *CachedProvider {
+	return &CachedProvider{provider: p, folder: folder, ttl: ttl, cost: cost,
+		stats: PlaceCacheStats{TTL: ttl.String(), Since: time.Now()}}
+}
+
+//Name gives back the name of the cached provider, the venues belong to it and not to the cache
+func (c *CachedProvider) Name() string {
+	return c.provider.Name()
+}
+
+//Search answers the query from the cache or from the provider
+func (c *CachedProvider) Search(query string) ([]PlaceCandidate, error) {
+	var res []PlaceCandidate
+	key := c.provider.Name() + "/search/" + strings.ToLower(strings.TrimSpace(query))
+	hit, _, err := c.cached("search", key, false, &res, func() (interface{}, error) {
+		return c.provider.Search(query)
+	})
+	c.count(hit, &c.stats.SearchHits, &c.stats.SearchMisses, c.cost.Search)
+	return res, err
+}
+
+//Details answers the request from the cache or from the provider
+func (c *CachedProvider) Details(placeid string) (PlaceDetails, error) {
+	res, _, err := c.DetailsFetched(placeid, false)
+	return res, err
+}
+
+//DetailsFetched answers the request like Details and tells when the response was fetched from the provider.
+//With fresh the provider is always asked and the cache only gets the new response
+func (c *CachedProvider) DetailsFetched(placeid string, fresh bool) (PlaceDetails, time.Time, error) {
+	var res PlaceDetails
+	key := c.provider.Name() + "/details/" + placeid
+	hit, fetched, err := c.cached("details", key, fresh, &res, func() (interface{}, error) {
+		return c.provider.Details(placeid)
+	})
+	c.count(hit, &c.stats.DetailsHits, &c.stats.DetailsMisses, c.cost.Details)
+	return res, fetched, err
+}
+
+//Stats gives back the statistics of the cache, the costs are rounded to a hundredth of a cent
+func (c *CachedProvider) Stats() PlaceCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	res := c.stats
+	res.EstimatedCost = math.Round(res.EstimatedCost*10000) / 10000
+	res.EstimatedSavings = math.Round(res.EstimatedSavings*10000) / 10000
+	return res
+}
+
+func (c *CachedProvider) count(hit bool, hits *int, misses *int, cost float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if hit {
+		*hits++
+		c.stats.EstimatedSavings += cost
+		return
+	}
+	*misses++
+	c.stats.EstimatedCost += cost
+}
+
+//cached decodes a fresh cache entry of the key into res, otherwise or if fresh is set it calls fetch and saves
+//its response. Next to whether the cache answered it gives back when the response was fetched.
+//Failed requests are not cached
+func (c *CachedProvider) cached(kind string, key string, fresh bool, res interface{},
+	fetch func() (interface{}, error)) (bool, time.Time, error) {
+	hash := sha256.Sum256([]byte(key))
+	filename := filepath.Join(c.folder, kind, hex.EncodeToString(hash[:])) + ".json"
+	if c.ttl > 0 && !fresh {
+		var entry placeCacheEntry
+		err := readJSONFile(filename, &entry)
+		if err == nil && entry.Key == key && time.Since(entry.Fetched) < c.ttl &&
+			json.Unmarshal(entry.Response, res) == nil {
+			return true, entry.Fetched, nil
+		}
+	}
+	response, err := fetch()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	fetched := time.Now()
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return false, fetched, errors.New("Error encoding response: " + err.Error())
+	}
+	err = json.Unmarshal(raw, res)
+	if err != nil {
+		return false, fetched, errors.New("Error decoding response: " + err.Error())
+	}
+	if c.ttl > 0 {
+		err = os.MkdirAll(filepath.Dir(filename), 0755)
+		if err == nil {
+			err = writeFileAtomic(filename, func(w io.Writer) error {
+				return json.NewEncoder(w).Encode(placeCacheEntry{Key: key, Fetched: fetched, Response: raw})
+			})
+		}
+		if err != nil {
+			c.mutex.Lock()
+			c.stats.SaveErrors++
+			c.stats.SaveError = "Error saving response in cache: " + err.Error()
+			c.mutex.Unlock()
+		}
+	}
+	return false, fetched, nil
+}
+
+func readJSONFile(filename string, v interface{}) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewDecoder(file).Decode(v)
+}
+
+//PlaceCacheStatistics gives back the statistics of the configured PlaceProvider if it is cached
+func PlaceCacheStatistics() (PlaceCacheStats, error) {
+	c, ok := provider.(*CachedProvider)
+	if !ok {
+		return PlaceCacheStats{}, ErrNoPlaceCache
+	}
+	return c.Stats(), nil
+}
@@ -0,0 +1,79 @@
+package venue
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+//countingProvider counts the requests reaching it and gives back a new phone number on every details request
+type countingProvider struct {
+	details int
+}
+
+func (p *countingProvider) Name() string {
+	return "counting"
+}
+
+func (p *countingProvider) Search(query string) ([]PlaceCandidate, error) {
+	return []PlaceCandidate{{PlaceID: "p1", Name: "Pizzeria"}}, nil
+}
+
+func (p *countingProvider) Details(placeid string) (PlaceDetails, error) {
+	p.details++
+	return PlaceDetails{PhoneNumber: string(rune('0' + p.details))}, nil
+}
+
+func TestRefreshBypassesPlaceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	counting := &countingProvider{}
+	c := NewCachedProvider(counting, dir, time.Hour, PlaceCost{})
+	SetPlaceProvider(c)
+	defer SetPlaceProvider(nil)
+
+	//adding a venue may use the cache, its LastRefresh is the time the details were fetched
+	_, fetched, err := c.DetailsFetched("p1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := GetVenuebyCandidate(PlaceCandidate{PlaceID: "p1", Name: "Pizzeria"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.details != 1 || v.PhoneNumber != "1" || !v.LastRefresh.Equal(fetched) {
+		t.Fatalf("venue from cached details: %d requests, phone %q, LastRefresh %s, want 1 request, phone 1, %s",
+			counting.details, v.PhoneNumber, v.LastRefresh, fetched)
+	}
+
+	//a refresh has to reach the provider although the cached response is still fresh
+	before := time.Now()
+	err = v.UpdateInfos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.details != 2 || v.PhoneNumber != "2" {
+		t.Errorf("refresh: %d requests and phone %q, want 2 requests and phone 2", counting.details, v.PhoneNumber)
+	}
+	if v.LastRefresh.Before(before) {
+		t.Errorf("LastRefresh %s of the refresh is older than the refresh itself", v.LastRefresh)
+	}
+
+	//the new response replaced the cached one
+	details, err := c.Details("p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counting.details != 2 || details.PhoneNumber != "2" {
+		t.Errorf("cached details after refresh: %d requests and phone %q, want 2 requests and phone 2",
+			counting.details, details.PhoneNumber)
+	}
+	stats := c.Stats()
+	if stats.DetailsHits != 2 || stats.DetailsMisses != 2 {
+		t.Errorf("stats = %d hits and %d misses, want 2 and 2", stats.DetailsHits, stats.DetailsMisses)
+	}
+}
@@ -0,0 +1,142 @@
+package venue
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+//maxCandidates is the number of search results given back by SearchPlaces
+const maxCandidates = 10
+
+const (
+	//BusinessOperational means the venue is open for business
+	BusinessOperational = "OPERATIONAL"
+	//BusinessClosedTemporarily means the venue is closed for now but is expected to open again
+	BusinessClosedTemporarily = "CLOSED_TEMPORARILY"
+	//BusinessClosedPermanently means the venue is gone
+	BusinessClosedPermanently = "CLOSED_PERMANENTLY"
+)
+
+//ErrNoPlaceProvider is returned if places should be looked up but no PlaceProvider is set up
+var ErrNoPlaceProvider = errors.New("No place provider configured")
+
+//PlaceProvider looks up places to eat in an external directory like Google Places or OpenStreetMap
+type PlaceProvider interface {
+	//Name is saved as PlaceProvider of the venues, so their PlaceID can be looked up again later
+	Name() string
+	//Search gives back the places matching the query ranked by relevance
+	Search(query string) ([]PlaceCandidate, error)
+	//Details gives back the volatile infos of the place with the given ID
+	Details(placeid string) (PlaceDetails, error)
+}
+
+//PlaceCandidate is one result of a search in the PlaceProvider the user can choose from
+type PlaceCandidate struct {
+	Rank    int
+	PlaceID string
+	Name    string
+	Address string
+	Rating  float64
+}
+
+//PlaceDetails holds the infos of a place which change over time
+type PlaceDetails struct {
+	OpeningHours     maps.OpeningHours
+	OpeningHoursText []string
+	Website          string
+	PhoneNumber      string
+	//BusinessStatus is one of the Business constants or empty if the provider doesn't know
+	BusinessStatus string
+}
+
+var provider PlaceProvider
+
+//SetPlaceProvider sets the provider used to search venues and update their infos
+func SetPlaceProvider(p PlaceProvider) {
+	provider = p
+}
+
+//PlaceProviderName gives back the name of the configured PlaceProvider or an empty string if there is none
+func PlaceProviderName() string {
+	if provider == nil {
+		return ""
+	}
+	return provider.Name()
+}
+
+//SearchPlaces queries the PlaceProvider with the query and gives back the candidates ranked by relevance
+func SearchPlaces(query string) ([]PlaceCandidate, error) {
+	if provider == nil {
+		return nil, ErrNoPlaceProvider
+	}
+	cc, err := provider.Search(query)
+	if err != nil {
+		return nil, errors.New("Error on search query:" + err.Error())
+	}
+	if len(cc) > maxCandidates {
+		cc = cc[:maxCandidates]
+	}
+	for i := range cc {
+		cc[i].Rank = i + 1
+	}
+	return cc, nil
+}
+
+//GetVenuebyCandidate builds a venue from the chosen candidate and fetches its details from the PlaceProvider
+func GetVenuebyCandidate(candidate PlaceCandidate) (Venue, error) {
+	var res Venue
+	res.Name = candidate.Name
+	res.Rating = int(math.Round(candidate.Rating))
+	res.PlaceProvider = PlaceProviderName()
+	res.PlaceID = candidate.PlaceID
+	res.Address = candidate.Address
+	//the details of a venue about to be added may come from the cache, LastRefresh tells how old they are
+	err := res.updateInfos(false)
+	if err != nil {
+		return res, errors.New("Error updating details:" + err.Error())
+	}
+	return res, nil
+}
+
+//UpdateInfos updates volatile Infos of a Venue (Opening Hours, Website, Phone Number). The PlaceProvider is always
+//asked, a cache in front of it only gets the new details
+func (v *Venue) UpdateInfos() error {
+	return v.updateInfos(true)
+}
+
+//updateInfos fetches the details of the venue, with fresh they don't come from the cache. LastRefresh is set to the
+//time they were fetched from the PlaceProvider
+func (v *Venue) updateInfos(fresh bool) error {
+	if provider == nil {
+		return ErrNoPlaceProvider
+	}
+	if v.PlaceProvider != provider.Name() {
+		return errors.New("Venue belongs to place provider " + v.PlaceProvider + " but " + provider.Name() + " is configured")
+	}
+	details, fetched, err := fetchDetails(v.PlaceID, fresh)
+	if err != nil {
+		return errors.New("Error on detail query:" + err.Error())
+	}
+
+	v.OpeningHours = details.OpeningHours
+	v.OpeningHoursText = details.OpeningHoursText
+	v.Website = details.Website
+	v.PhoneNumber = details.PhoneNumber
+	v.BusinessStatus = details.BusinessStatus
+	v.LastRefresh = fetched
+
+	return nil
+}
+
+//fetchDetails asks the configured PlaceProvider for the details of the place and tells when they were fetched.
+//Unless fresh is set a cached response may be given back
+func fetchDetails(placeid string, fresh bool) (PlaceDetails, time.Time, error) {
+	if c, ok := provider.(*CachedProvider); ok {
+		return c.DetailsFetched(placeid, fresh)
+	}
+	details, err := provider.Details(placeid)
+	return details, time.Now(), err
+}
@@ -0,0 +1,696 @@
+package venue
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	//registers the sqlite3 driver for database/sql
+	_ "github.com/mattn/go-sqlite3"
+	"googlemaps.github.io/maps"
+)
+
+//sqliteMigrations holds the schema changes of the database in order. The index+1 of the last applied
+//migration is saved as user_version inside the database, so never change or reorder existing entries
+var sqliteMigrations = []string{
+	`CREATE TABLE venues (
+		venue_id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		address TEXT NOT NULL,
+		rating INTEGER NOT NULL,
+		google_place_id TEXT NOT NULL,
+		open_now INTEGER,
+		permanently_closed INTEGER,
+		weekday_text TEXT NOT NULL,
+		opening_hours_text TEXT NOT NULL,
+		website TEXT NOT NULL,
+		phone_number TEXT NOT NULL,
+		notes TEXT NOT NULL
+	);
+	CREATE TABLE visits (
+		venue_id TEXT NOT NULL REFERENCES venues(venue_id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		visited_at TEXT NOT NULL,
+		PRIMARY KEY (venue_id, position)
+	);
+	CREATE TABLE opening_hours_periods (
+		venue_id TEXT NOT NULL REFERENCES venues(venue_id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		open_day INTEGER NOT NULL,
+		open_time TEXT NOT NULL,
+		close_day INTEGER NOT NULL,
+		close_time TEXT NOT NULL,
+		PRIMARY KEY (venue_id, position)
+	);`,
+	`CREATE TABLE venue_aliases (
+		alias TEXT PRIMARY KEY,
+		venue_id TEXT NOT NULL REFERENCES venues(venue_id) ON DELETE CASCADE
+	);`,
+	`ALTER TABLE venues ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE visits ADD COLUMN attendees TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE visits ADD COLUMN rating INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE visits ADD COLUMN dishes TEXT NOT NULL DEFAULT '[]';
+	ALTER TABLE visits ADD COLUMN cost REAL NOT NULL DEFAULT 0;
+	ALTER TABLE visits ADD COLUMN currency TEXT NOT NULL DEFAULT '';
+	ALTER TABLE visits ADD COLUMN comment TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE visits ADD COLUMN visit_id TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE venues RENAME COLUMN google_place_id TO place_id;
+	ALTER TABLE venues ADD COLUMN place_provider TEXT NOT NULL DEFAULT '';
+	UPDATE venues SET place_provider = 'google' WHERE place_id <> '';`,
+	`ALTER TABLE venues ADD COLUMN last_refresh TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE venues ADD COLUMN business_status TEXT NOT NULL DEFAULT '';
+	ALTER TABLE venues ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;`,
+	`CREATE TABLE venue_history (
+		venue_id TEXT NOT NULL REFERENCES venues(venue_id) ON DELETE CASCADE,
+		changed_at TEXT NOT NULL,
+		field TEXT NOT NULL,
+		old_value TEXT NOT NULL,
+		new_value TEXT NOT NULL,
+		source TEXT NOT NULL
+	);
+	CREATE INDEX venue_history_venue_id ON venue_history (venue_id);`,
+	`ALTER TABLE venues ADD COLUMN closed_on_holidays INTEGER NOT NULL DEFAULT 0;
+	CREATE TABLE venue_exceptions (
+		venue_id TEXT NOT NULL REFERENCES venues(venue_id) ON DELETE CASCADE,
+		position INTEGER NOT NULL,
+		from_day TEXT NOT NULL,
+		to_day TEXT NOT NULL,
+		closed INTEGER NOT NULL,
+		open_time TEXT NOT NULL,
+		close_time TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		PRIMARY KEY (venue_id, position)
+	);`,
+	//databases with venues were filled before the import was recorded, importing again would bring back deleted venues
+	`CREATE TABLE store_meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	INSERT INTO store_meta (key, value) SELECT 'json_import', '' WHERE EXISTS (SELECT 1 FROM venues);`,
+	`ALTER TABLE venues ADD COLUMN closed_all_week INTEGER NOT NULL DEFAULT 0;`,
+}
+
+const venueColumns = `venue_id, name, address, rating, place_provider, place_id, open_now, permanently_closed,
+	weekday_text, opening_hours_text, website, phone_number, notes, revision, last_refresh,
+	business_status, archived, closed_on_holidays, closed_all_week`
+
+//SQLiteStore is a VenueStore keeping all venues in one SQLite database
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+//querier is implemented by *sql.DB and *sql.Tx, so venues can be loaded inside and outside of transactions
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+//NewSQLiteStore opens the database at the given path, creates it if needed and migrates it to the current schema
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=1&_busy_timeout=5000")
+	if err != nil {
+		return nil, errors.New("Error opening database: " + err.Error())
+	}
+	db.SetMaxOpenConns(1)
+	s := &SQLiteStore{db: db}
+	err = s.migrate()
+	if err != nil {
+		db.Close()
+		return nil, errors.New("Error migrating database: " + err.Error())
+	}
+	return s, nil
+}
+
+//Close closes the underlying database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	err := s.db.QueryRow("PRAGMA user_version").Scan(&version)
+	if err != nil {
+		return err
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(sqliteMigrations[i])
+		if err != nil {
+			tx.Rollback()
+			return errors.New("Migration " + strconv.Itoa(i+1) + ": " + err.Error())
+		}
+		_, err = tx.Exec("PRAGMA user_version = " + strconv.Itoa(i+1))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Get loads the venue with the given ID from the database
+func (s *SQLiteStore) Get(id string) (Venue, error) {
+	return getVenue(s.db, id)
+}
+
+//resolveID gives back the ID of the venue the given ID or alias belongs to
+func resolveID(q querier, id string) (string, error) {
+	var target string
+	err := q.QueryRow(`SELECT venue_id FROM venues WHERE venue_id = ?
+		UNION ALL SELECT venue_id FROM venue_aliases WHERE alias = ? LIMIT 1`, id, id).Scan(&target)
+	if err == sql.ErrNoRows {
+		return id, nil
+	}
+	if err != nil {
+		return id, errors.New("Error resolving venue ID: " + err.Error())
+	}
+	return target, nil
+}
+
+func getVenue(q querier, id string) (Venue, error) {
+	id, err := resolveID(q, id)
+	if err != nil {
+		return Venue{}, err
+	}
+	vv, err := selectVenues(q, "WHERE venue_id = ?", id)
+	if err != nil {
+		return Venue{}, err
+	}
+	if len(vv) == 0 {
+		return Venue{}, ErrVenueNotFound
+	}
+	return vv[0], nil
+}
+
+//Put creates or overwrites the venue together with its visits and opening hours
+func (s *SQLiteStore) Put(v Venue) error {
+	if v.VenueID == "" {
+		return errors.New("Venue has no ID")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("Error starting transaction: " + err.Error())
+	}
+	err = putVenue(tx, v)
+	if err != nil {
+		tx.Rollback()
+		return errors.New("Error saving venue: " + err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("Error committing venue: " + err.Error())
+	}
+	return nil
+}
+
+//Update loads the venue, applies fn and saves it again inside one transaction
+func (s *SQLiteStore) Update(id string, fn func(v *Venue) error) (Venue, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Venue{}, errors.New("Error starting transaction: " + err.Error())
+	}
+	v, err := getVenue(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return v, err
+	}
+	id = v.VenueID
+	err = fn(&v)
+	if err != nil {
+		tx.Rollback()
+		return v, err
+	}
+	if v.VenueID != id {
+		tx.Rollback()
+		return v, ErrVenueIDChanged
+	}
+	v.Revision++
+	v.AssignVisitIDs()
+	err = putVenue(tx, v)
+	if err != nil {
+		tx.Rollback()
+		return v, errors.New("Error saving venue: " + err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return v, errors.New("Error committing venue: " + err.Error())
+	}
+	return v, nil
+}
+
+//Delete removes the venue and everything belonging to it from the database
+func (s *SQLiteStore) Delete(id string, check func(v Venue) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("Error starting transaction: " + err.Error())
+	}
+	v, err := getVenue(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if check != nil {
+		err = check(v)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM venues WHERE venue_id = ?", v.VenueID)
+	if err != nil {
+		tx.Rollback()
+		return errors.New("Error deleting venue: " + err.Error())
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("Error committing deletion: " + err.Error())
+	}
+	return nil
+}
+
+//PutAlias saves the alias for the venue in the alias table
+func (s *SQLiteStore) PutAlias(alias string, id string) error {
+	_, err := s.db.Exec(`INSERT INTO venue_aliases (alias, venue_id) VALUES (?, ?)
+		ON CONFLICT (alias) DO UPDATE SET venue_id = excluded.venue_id`, alias, id)
+	if err != nil {
+		return errors.New("Error saving alias: " + err.Error())
+	}
+	return nil
+}
+
+//JSONImported tells if a JSON data folder was imported into the database already
+func (s *SQLiteStore) JSONImported() (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM store_meta WHERE key = 'json_import'").Scan(&n)
+	if err != nil {
+		return false, errors.New("Error reading store meta data: " + err.Error())
+	}
+	return n > 0, nil
+}
+
+//MarkJSONImported records that the JSON data folder was imported, so it is never imported again
+func (s *SQLiteStore) MarkJSONImported(folder string) error {
+	_, err := s.db.Exec(`INSERT INTO store_meta (key, value) VALUES ('json_import', ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, folder)
+	if err != nil {
+		return errors.New("Error saving store meta data: " + err.Error())
+	}
+	return nil
+}
+
+//MarkRefreshed sets the LastRefresh of the venue without touching anything else
+func (s *SQLiteStore) MarkRefreshed(id string, at time.Time) error {
+	id, err := resolveID(s.db, id)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec("UPDATE venues SET last_refresh = ? WHERE venue_id = ?", formatRefresh(at), id)
+	if err != nil {
+		return errors.New("Error saving last refresh: " + err.Error())
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.New("Error saving last refresh: " + err.Error())
+	}
+	if n == 0 {
+		return ErrVenueNotFound
+	}
+	return nil
+}
+
+//existingID resolves the ID and makes sure the venue exists
+func existingID(q querier, id string) (string, error) {
+	id, err := resolveID(q, id)
+	if err != nil {
+		return id, err
+	}
+	var found string
+	err = q.QueryRow("SELECT venue_id FROM venues WHERE venue_id = ?", id).Scan(&found)
+	if err == sql.ErrNoRows {
+		return id, ErrVenueNotFound
+	}
+	if err != nil {
+		return id, errors.New("Error loading venue: " + err.Error())
+	}
+	return id, nil
+}
+
+//AddHistory saves the changes of the venue in the order given
+func (s *SQLiteStore) AddHistory(id string, changes []Change) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("Error starting transaction: " + err.Error())
+	}
+	id, err = existingID(tx, id)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, c := range changes {
+		_, err = tx.Exec(`INSERT INTO venue_history (venue_id, changed_at, field, old_value, new_value, source)
+			VALUES (?, ?, ?, ?, ?, ?)`, id, c.Time.Format(time.RFC3339Nano), c.Field, c.Old, c.New, c.Source)
+		if err != nil {
+			tx.Rollback()
+			return errors.New("Error saving history: " + err.Error())
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.New("Error committing history: " + err.Error())
+	}
+	return nil
+}
+
+//History loads the changes of the venue in the order they were saved
+func (s *SQLiteStore) History(id string) ([]Change, error) {
+	id, err := existingID(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT changed_at, field, old_value, new_value, source FROM venue_history
+		WHERE venue_id = ? ORDER BY rowid`, id)
+	if err != nil {
+		return nil, errors.New("Error querying history: " + err.Error())
+	}
+	defer rows.Close()
+	history := []Change{}
+	for rows.Next() {
+		var c Change
+		var changedat string
+		err = rows.Scan(&changedat, &c.Field, &c.Old, &c.New, &c.Source)
+		if err != nil {
+			return nil, errors.New("Error reading history: " + err.Error())
+		}
+		c.Time, err = time.Parse(time.RFC3339Nano, changedat)
+		if err != nil {
+			return nil, errors.New("Error decoding history time: " + err.Error())
+		}
+		history = append(history, c)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("Error querying history: " + err.Error())
+	}
+	return history, nil
+}
+
+//formatRefresh saves a venue which was never refreshed as empty string
+func formatRefresh(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+//List gives back all venues in the database
+func (s *SQLiteStore) List() ([]Venue, error) {
+	return selectVenues(s.db, "")
+}
+
+//Query gives back all venues matching the query, the filtering is done by the database
+func (s *SQLiteStore) Query(q Query) ([]Venue, error) {
+	where := "WHERE 1 = 1"
+	if q.OnlyVisited {
+		where += " AND EXISTS (SELECT 1 FROM visits WHERE visits.venue_id = venues.venue_id)"
+	}
+	if q.OnlyNotVisited {
+		where += " AND NOT EXISTS (SELECT 1 FROM visits WHERE visits.venue_id = venues.venue_id)"
+	}
+	var args []interface{}
+	if q.OnlyWithPlaceID {
+		where += " AND place_id <> ''"
+	}
+	if q.PlaceProvider != "" {
+		where += " AND place_provider = ?"
+		args = append(args, q.PlaceProvider)
+	}
+	if q.ExcludeClosed {
+		where += " AND business_status NOT IN (?, ?)"
+		args = append(args, BusinessClosedTemporarily, BusinessClosedPermanently)
+	}
+	if q.ExcludeArchived {
+		where += " AND archived = 0"
+	}
+	return selectVenues(s.db, where, args...)
+}
+
+func nullBool(b *bool) sql.NullBool {
+	if b == nil {
+		return sql.NullBool{}
+	}
+	return sql.NullBool{Bool: *b, Valid: true}
+}
+
+func boolPointer(b sql.NullBool) *bool {
+	if !b.Valid {
+		return nil
+	}
+	return &b.Bool
+}
+
+func putVenue(tx *sql.Tx, v Venue) error {
+	v.AssignVisitIDs()
+	weekdaytext, err := json.Marshal(v.OpeningHours.WeekdayText)
+	if err != nil {
+		return err
+	}
+	openinghourstext, err := json.Marshal(v.OpeningHoursText)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO venues (`+venueColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (venue_id) DO UPDATE SET name = excluded.name, address = excluded.address,
+		rating = excluded.rating, place_provider = excluded.place_provider, place_id = excluded.place_id,
+		open_now = excluded.open_now,
+		permanently_closed = excluded.permanently_closed, weekday_text = excluded.weekday_text,
+		opening_hours_text = excluded.opening_hours_text, website = excluded.website,
+		phone_number = excluded.phone_number, notes = excluded.notes, revision = excluded.revision,
+		last_refresh = excluded.last_refresh, business_status = excluded.business_status,
+		archived = excluded.archived, closed_on_holidays = excluded.closed_on_holidays,
+		closed_all_week = excluded.closed_all_week`,
+		v.VenueID, v.Name, v.Address, v.Rating, v.PlaceProvider, v.PlaceID, nullBool(v.OpeningHours.OpenNow),
+		nullBool(v.OpeningHours.PermanentlyClosed), string(weekdaytext), string(openinghourstext),
+		v.Website, v.PhoneNumber, v.Notes, v.Revision, formatRefresh(v.LastRefresh),
+		v.BusinessStatus, v.Archived, v.ClosedOnHolidays, v.ClosedAllWeek)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("DELETE FROM visits WHERE venue_id = ?", v.VenueID)
+	if err != nil {
+		return err
+	}
+	for i, visit := range v.Visits {
+		attendees, err := json.Marshal(visit.Attendees)
+		if err != nil {
+			return err
+		}
+		dishes, err := json.Marshal(visit.Dishes)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO visits (venue_id, position, visit_id, visited_at, attendees, rating, dishes, cost, currency, comment)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, v.VenueID, i, visit.VisitID, visit.Date.Format(time.RFC3339Nano),
+			string(attendees), visit.Rating, string(dishes), visit.Cost, visit.Currency, visit.Comment)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM opening_hours_periods WHERE venue_id = ?", v.VenueID)
+	if err != nil {
+		return err
+	}
+	for i, p := range v.OpeningHours.Periods {
+		_, err = tx.Exec(`INSERT INTO opening_hours_periods (venue_id, position, open_day, open_time, close_day, close_time)
+			VALUES (?, ?, ?, ?, ?, ?)`, v.VenueID, i, int(p.Open.Day), p.Open.Time, int(p.Close.Day), p.Close.Time)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec("DELETE FROM venue_exceptions WHERE venue_id = ?", v.VenueID)
+	if err != nil {
+		return err
+	}
+	for i, e := range v.Exceptions {
+		_, err = tx.Exec(`INSERT INTO venue_exceptions (venue_id, position, from_day, to_day, closed, open_time, close_time, reason)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, v.VenueID, i, e.From.Format(layoutDate), e.To.Format(layoutDate), e.Closed,
+			e.Open, e.Close, e.Reason)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//selectVenues loads all venues matching the where clause and fills in their visits and opening hours
+func selectVenues(q querier, where string, args ...interface{}) ([]Venue, error) {
+	rows, err := q.Query("SELECT "+venueColumns+" FROM venues "+where, args...)
+	if err != nil {
+		return nil, errors.New("Error querying venues: " + err.Error())
+	}
+	defer rows.Close()
+
+	var result []Venue
+	index := make(map[string]int)
+	for rows.Next() {
+		var v Venue
+		var opennow, permanentlyclosed sql.NullBool
+		var weekdaytext, openinghourstext, lastrefresh string
+		err = rows.Scan(&v.VenueID, &v.Name, &v.Address, &v.Rating, &v.PlaceProvider, &v.PlaceID, &opennow,
+			&permanentlyclosed, &weekdaytext, &openinghourstext, &v.Website, &v.PhoneNumber, &v.Notes, &v.Revision, &lastrefresh,
+			&v.BusinessStatus, &v.Archived, &v.ClosedOnHolidays, &v.ClosedAllWeek)
+		if err != nil {
+			return nil, errors.New("Error reading venue: " + err.Error())
+		}
+		if lastrefresh != "" {
+			v.LastRefresh, err = time.Parse(time.RFC3339Nano, lastrefresh)
+			if err != nil {
+				return nil, errors.New("Error decoding last refresh: " + err.Error())
+			}
+		}
+		v.OpeningHours.OpenNow = boolPointer(opennow)
+		v.OpeningHours.PermanentlyClosed = boolPointer(permanentlyclosed)
+		err = json.Unmarshal([]byte(weekdaytext), &v.OpeningHours.WeekdayText)
+		if err != nil {
+			return nil, errors.New("Error decoding weekday text: " + err.Error())
+		}
+		err = json.Unmarshal([]byte(openinghourstext), &v.OpeningHoursText)
+		if err != nil {
+			return nil, errors.New("Error decoding opening hours text: " + err.Error())
+		}
+		index[v.VenueID] = len(result)
+		result = append(result, v)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.New("Error querying venues: " + err.Error())
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	err = fillVisits(q, result, index)
+	if err != nil {
+		return nil, err
+	}
+	err = fillPeriods(q, result, index)
+	if err != nil {
+		return nil, err
+	}
+	err = fillExceptions(q, result, index)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//childFilter limits the queries for visits and opening hours to a single venue if only one was loaded
+func childFilter(vv []Venue) (string, []interface{}) {
+	if len(vv) == 1 {
+		return "WHERE venue_id = ?", []interface{}{vv[0].VenueID}
+	}
+	return "", nil
+}
+
+func fillVisits(q querier, vv []Venue, index map[string]int) error {
+	where, args := childFilter(vv)
+	rows, err := q.Query(`SELECT venue_id, visit_id, visited_at, attendees, rating, dishes, cost, currency, comment
+		FROM visits `+where+` ORDER BY venue_id, position`, args...)
+	if err != nil {
+		return errors.New("Error querying visits: " + err.Error())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, visitedat, attendees, dishes string
+		var visit Visit
+		err = rows.Scan(&id, &visit.VisitID, &visitedat, &attendees, &visit.Rating, &dishes, &visit.Cost, &visit.Currency, &visit.Comment)
+		if err != nil {
+			return errors.New("Error reading visit: " + err.Error())
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		visit.Date, err = time.Parse(time.RFC3339Nano, visitedat)
+		if err != nil {
+			return errors.New("Error parsing visit: " + err.Error())
+		}
+		err = json.Unmarshal([]byte(attendees), &visit.Attendees)
+		if err != nil {
+			return errors.New("Error decoding attendees: " + err.Error())
+		}
+		err = json.Unmarshal([]byte(dishes), &visit.Dishes)
+		if err != nil {
+			return errors.New("Error decoding dishes: " + err.Error())
+		}
+		vv[i].Visits = append(vv[i].Visits, visit)
+	}
+	return rows.Err()
+}
+
+func fillPeriods(q querier, vv []Venue, index map[string]int) error {
+	where, args := childFilter(vv)
+	rows, err := q.Query(`SELECT venue_id, open_day, open_time, close_day, close_time
+		FROM opening_hours_periods `+where+` ORDER BY venue_id, position`, args...)
+	if err != nil {
+		return errors.New("Error querying opening hours: " + err.Error())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var openday, closeday int
+		var p maps.OpeningHoursPeriod
+		err = rows.Scan(&id, &openday, &p.Open.Time, &closeday, &p.Close.Time)
+		if err != nil {
+			return errors.New("Error reading opening hours: " + err.Error())
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		p.Open.Day = time.Weekday(openday)
+		p.Close.Day = time.Weekday(closeday)
+		vv[i].OpeningHours.Periods = append(vv[i].OpeningHours.Periods, p)
+	}
+	return rows.Err()
+}
+
+func fillExceptions(q querier, vv []Venue, index map[string]int) error {
+	where, args := childFilter(vv)
+	rows, err := q.Query(`SELECT venue_id, from_day, to_day, closed, open_time, close_time, reason
+		FROM venue_exceptions `+where+` ORDER BY venue_id, position`, args...)
+	if err != nil {
+		return errors.New("Error querying exceptions: " + err.Error())
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, from, to string
+		var e Exception
+		err = rows.Scan(&id, &from, &to, &e.Closed, &e.Open, &e.Close, &e.Reason)
+		if err != nil {
+			return errors.New("Error reading exception: " + err.Error())
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		e.From, err = time.Parse(layoutDate, from)
+		if err != nil {
+			return errors.New("Error parsing exception: " + err.Error())
+		}
+		e.To, err = time.Parse(layoutDate, to)
+		if err != nil {
+			return errors.New("Error parsing exception: " + err.Error())
+		}
+		vv[i].Exceptions = append(vv[i].Exceptions, e)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,35 @@
+package venue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStoreRecordsJSONImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wheretoeat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewSQLiteStore(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	imported, err := s.JSONImported()
+	if err != nil || imported {
+		t.Fatalf("JSONImported() = %v, %v on a new database, want false", imported, err)
+	}
+	err = s.MarkJSONImported("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//deleting every venue afterwards must not trigger another import
+	imported, err = s.JSONImported()
+	if err != nil || !imported {
+		t.Fatalf("JSONImported() = %v, %v after the import, want true", imported, err)
+	}
+}
@@ -0,0 +1,104 @@
+package venue
+
+import (
+	"errors"
+	"time"
+)
+
+//ErrVenueNotFound is returned by a VenueStore if there is no venue with the requested ID
+var ErrVenueNotFound = errors.New("Venue not found")
+
+//ErrVisitNotFound is returned if the venue has no visit with the requested ID
+var ErrVisitNotFound = errors.New("Visit not found")
+
+//ErrVenueIDChanged is returned by Update if the update function tried to change the ID of the venue
+var ErrVenueIDChanged = errors.New("Venue ID must not be changed by an update")
+
+//ErrRevisionMismatch is returned if a venue should be changed but does not have the expected revision anymore
+var ErrRevisionMismatch = errors.New("Venue was changed by someone else")
+
+//VenueStore is implemented by every backend that is able to persist venues.
+//Get, Update and Delete also accept an alias of a venue ID
+type VenueStore interface {
+	//Get loads the venue with the given ID
+	Get(id string) (Venue, error)
+	//Put creates or overwrites the venue under its VenueID
+	Put(v Venue) error
+	//Update loads the venue, hands it to fn and saves the result with an increased Revision, all while holding
+	//the venue exclusively. If fn returns an error nothing is saved
+	Update(id string, fn func(v *Venue) error) (Venue, error)
+	//Delete removes the venue with the given ID. If check is not nil it is called with the venue first
+	//while holding it exclusively, and the venue is only removed if check returns no error
+	Delete(id string, check func(v Venue) error) error
+	//List gives back all venues of the store
+	List() ([]Venue, error)
+	//Query gives back all venues matching the query
+	Query(q Query) ([]Venue, error)
+	//PutAlias makes the venue with the given ID reachable under the alias aswell
+	PutAlias(alias string, id string) error
+	//MarkRefreshed sets the LastRefresh of the venue without increasing its Revision. A refresh which found
+	//nothing new is no change someone editing the venue has to know about
+	MarkRefreshed(id string, at time.Time) error
+	//AddHistory appends the changes to the history of the venue
+	AddHistory(id string, changes []Change) error
+	//History gives back the recorded changes of the venue, the oldest first
+	History(id string) ([]Change, error)
+}
+
+//StoreProblem describes an entry of a VenueStore that could not be loaded
+type StoreProblem struct {
+	File        string    `json:"file"`
+	Error       string    `json:"error"`
+	Quarantined string    `json:"quarantined,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+//HealthReporter is implemented by every VenueStore that is able to report problems with its data
+type HealthReporter interface {
+	//Problems gives back every known entry that could not be loaded
+	Problems() []StoreProblem
+}
+
+//Query holds the criteria to filter the venues of a VenueStore. The zero value matches every venue
+type Query struct {
+	OnlyVisited     bool
+	OnlyNotVisited  bool
+	OnlyWithPlaceID bool
+	//PlaceProvider only matches venues whose PlaceID belongs to this provider, if it is set
+	PlaceProvider   string
+	ExcludeClosed   bool
+	ExcludeArchived bool
+}
+
+//Matches checks if a venue fulfills all criteria of the query
+func (q Query) Matches(v Venue) bool {
+	if q.OnlyVisited && len(v.Visits) == 0 {
+		return false
+	}
+	if q.OnlyNotVisited && len(v.Visits) > 0 {
+		return false
+	}
+	if q.OnlyWithPlaceID && v.PlaceID == "" {
+		return false
+	}
+	if q.PlaceProvider != "" && v.PlaceProvider != q.PlaceProvider {
+		return false
+	}
+	if q.ExcludeClosed && v.Closed() {
+		return false
+	}
+	if q.ExcludeArchived && v.Archived {
+		return false
+	}
+	return true
+}
+
+func filterVenues(vv []Venue, q Query) []Venue {
+	var result []Venue
+	for _, v := range vv {
+		if q.Matches(v) {
+			result = append(result, v)
+		}
+	}
+	return result
+}
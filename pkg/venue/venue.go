@@ -1,34 +1,99 @@
 package venue
 
 import (
-	"context"
-	"crypto/sha1"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
-	"io/ioutil"
-	"math"
-	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"googlemaps.github.io/maps"
 )
 
 //Venue hod all information for a place to eat
 type Venue struct {
-	VenueID          string
-	Name             string
-	Address          string
-	Rating           int
-	GooglePlaceID    string
+	VenueID  string
+	Revision int
+	Name     string
+	Address  string
+	Rating   int
+	//PlaceProvider is the name of the PlaceProvider the PlaceID belongs to
+	PlaceProvider    string
+	PlaceID          string
 	OpeningHours     maps.OpeningHours
 	OpeningHoursText []string
 	Website          string
 	PhoneNumber      string
 	Notes            string
-	Visits           []time.Time
+	Visits           []Visit
+	//LastRefresh is when the infos were fetched from the PlaceProvider the last time
+	LastRefresh time.Time
+	//BusinessStatus is one of the Business constants as reported by the PlaceProvider, empty if unknown
+	BusinessStatus string
+	//Archived venues are kept with their visits but not shown or picked anymore
+	Archived bool
+	//Exceptions are closures for holidays and the like or openings on days the venue is closed usually
+	Exceptions []Exception
+	//ClosedOnHolidays keeps the venue closed on the public holidays
+	ClosedOnHolidays bool
+	//ClosedAllWeek tells that the venue has no regular opening hours at all and only opens on exceptions.
+	//Without it a venue without periods has unknown opening hours
+	ClosedAllWeek bool
+}
+
+//plainVenue has no methods, so decoding it doesn't end up in UnmarshalJSON again
+type plainVenue Venue
+
+//UnmarshalJSON decodes a venue. Venues used to only know Google Places and saved their ID as GooglePlaceID,
+//such an ID is still accepted
+func (v *Venue) UnmarshalJSON(data []byte) error {
+	var p struct {
+		plainVenue
+		GooglePlaceID string
+	}
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	*v = withGooglePlaceID(Venue(p.plainVenue), p.GooglePlaceID)
+	return nil
+}
+
+//StrictVenue decodes like a Venue but has no UnmarshalJSON, so json.Decoder.DisallowUnknownFields reaches all
+//of its fields including the ones of the visits. Visits saved as bare timestamps are not accepted
+type StrictVenue struct {
+	plainVenue
+	GooglePlaceID string
+	Visits        []StrictVisit
+}
+
+//StrictVisit decodes like a Visit but has no UnmarshalJSON, see StrictVenue
+type StrictVisit Visit
+
+//Venue gives back the decoded venue
+func (s StrictVenue) Venue() Venue {
+	v := Venue(s.plainVenue)
+	v.Visits = nil
+	if s.Visits != nil {
+		v.Visits = make([]Visit, len(s.Visits))
+		for i, visit := range s.Visits {
+			v.Visits[i] = Visit(visit)
+		}
+	}
+	return withGooglePlaceID(v, s.GooglePlaceID)
+}
+
+func withGooglePlaceID(v Venue, googleplaceid string) Venue {
+	if v.PlaceID == "" && googleplaceid != "" {
+		v.PlaceProvider = GoogleProviderName
+		v.PlaceID = googleplaceid
+	}
+	return v
+}
+
+//Closed tells if the PlaceProvider reported the venue as closed, be it temporarily or permanently
+func (v Venue) Closed() bool {
+	return v.BusinessStatus == BusinessClosedTemporarily || v.BusinessStatus == BusinessClosedPermanently
 }
 
 //ByName is for sorting Venues by Name
@@ -73,196 +138,88 @@ type quickQueryResponse struct {
 	Status string `json:"status"`
 }
 
-const searchqueryfields = "formatted_address,name,place_id,rating"
-const detailqueryfields = "opening_hours,website,international_phone_number"
-
-var client *maps.Client
-var searchqueryfieldsmask []maps.PlaceSearchFieldMask
-var detailqueryfieldsmask []maps.PlaceDetailsFieldMask
-
-var datafolder string
-
-func parseSearchFields(fields string) ([]maps.PlaceSearchFieldMask, error) {
-	var res []maps.PlaceSearchFieldMask
-	for _, s := range strings.Split(fields, ",") {
-		f, err := maps.ParsePlaceSearchFieldMask(s)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, f)
-	}
-	return res, nil
+//ValidationError describes why a venue can't be saved
+type ValidationError struct {
+	Field   string
+	Message string
 }
 
-func parseDetailFields(fields string) ([]maps.PlaceDetailsFieldMask, error) {
-	var res []maps.PlaceDetailsFieldMask
-	for _, s := range strings.Split(fields, ",") {
-		f, err := maps.ParsePlaceDetailsFieldMask(s)
-		if err != nil {
-			return nil, err
-		}
-		res = append(res, f)
-	}
-	return res, nil
+func (e ValidationError) Error() string {
+	return e.Field + ": " + e.Message
 }
 
-//SetupPlaceAPI Setups the API key for the client and the fields for the search
-func SetupPlaceAPI(apikey string) error {
-	var err error
-	client, err = maps.NewClient(maps.WithAPIKey(apikey))
-	if err != nil {
-		return errors.New("Error setting up client:" + err.Error())
+func validTime(t string) bool {
+	if len(t) != 4 {
+		return false
 	}
-	searchqueryfieldsmask, err = parseSearchFields(searchqueryfields)
+	hours, err := strconv.Atoi(t[:2])
 	if err != nil {
-		return errors.New("Error populating search fieldmask:" + err.Error())
+		return false
 	}
-	detailqueryfieldsmask, err = parseDetailFields(detailqueryfields)
+	minutes, err := strconv.Atoi(t[2:])
 	if err != nil {
-		return errors.New("Error populating seardetail fieldmask:" + err.Error())
+		return false
 	}
-	return nil
-}
-
-//SetDataFolder sets the folder where the json Files reside
-func SetDataFolder(venuedatafolder string) {
-	datafolder = venuedatafolder + string(os.PathSeparator)
+	return hours >= 0 && hours < 24 && minutes >= 0 && minutes < 60
 }
 
-//GenerateVenueID takes the Name and the Adress and builds the id from it
-func (v *Venue) GenerateVenueID() string {
-	hasher := sha1.New()
-	hasher.Write([]byte(v.Name + v.Address))
-	return base64.URLEncoding.EncodeToString(hasher.Sum(nil))
-}
-
-//GetVenubyPlaceSearch takes the query and queries the googleplaces api with it
-func GetVenubyPlaceSearch(query string) (Venue, error) {
-	var res Venue
-
-	searchRequest := &maps.FindPlaceFromTextRequest{
-		Input:     query,
-		InputType: maps.FindPlaceFromTextInputTypeTextQuery,
-		Fields:    searchqueryfieldsmask,
-	}
-
-	searchResp, err := client.FindPlaceFromText(context.Background(), searchRequest)
-	if err != nil {
-		return res, errors.New("Error on search query:" + err.Error())
+//Validate checks if the venue is complete and consistent enough to be saved
+func (v Venue) Validate() error {
+	if strings.TrimSpace(v.Name) == "" {
+		return ValidationError{"Name", "must not be empty"}
 	}
-
-	if len(searchResp.Candidates) == 0 {
-		return res, nil
+	if v.Rating < 0 || v.Rating > 5 {
+		return ValidationError{"Rating", "must be between 0 and 5"}
 	}
-
-	candidate := searchResp.Candidates[0]
-	res.Name = candidate.Name
-	res.Rating = int(math.Round(float64(candidate.Rating)))
-	res.GooglePlaceID = candidate.PlaceID
-	res.Address = candidate.FormattedAddress
-
-	err = res.UpdateInfos()
-	if err != nil {
-		return res, errors.New("Error updating details:" + err.Error())
+	if v.PlaceID != "" && v.PlaceProvider == "" {
+		return ValidationError{"PlaceProvider", "must not be empty if there is a PlaceID"}
 	}
-
-	return res, nil
-}
-
-func (v *Venue) getJSONFile() string {
-	return filepath.Join(datafolder, v.VenueID) + ".json"
-}
-
-//LoadFromDataLocation loads the JSON File of the Venue from the Data Location
-func (v *Venue) LoadFromDataLocation() error {
-	return v.loadfromFile(v.getJSONFile())
-}
-
-//SavetoDataLocation saves the JSON File of the Venue to the Data Location
-func (v *Venue) SavetoDataLocation() error {
-	return v.savetoFile(v.getJSONFile())
-}
-
-//SavetoFile save a venue to a json File
-func (v *Venue) savetoFile(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return errors.New("Error creating file: " + err.Error())
-	}
-	defer file.Close()
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(v)
-	if err != nil {
-		return errors.New("Error saving file: " + err.Error())
-	}
-	return nil
-}
-
-//LoadfromFile Loads a venue from a json File
-func (v *Venue) loadfromFile(filename string) error {
-	file, err := os.Open(filename)
-	if err != nil {
-		return errors.New("Error opening file: " + err.Error())
-	}
-	defer file.Close()
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(v)
-	if err != nil {
-		return errors.New("Error decoding file: " + err.Error())
-	}
-	return nil
-}
-
-//Delete Removes the Venue file from the Drive
-func (v *Venue) Delete() error {
-	err := os.Remove(v.getJSONFile())
-	if err != nil {
-		return errors.New("Error deleting file: " + err.Error())
-	}
-	return nil
-}
-
-//UpdateInfos updates volatile Infos of a Venue (Opening Hours, Website, Phone Number)
-func (v *Venue) UpdateInfos() error {
-	detailRequest := &maps.PlaceDetailsRequest{
-		PlaceID: v.GooglePlaceID,
-		Fields:  detailqueryfieldsmask,
-	}
-
-	detailResp, err := client.PlaceDetails(context.Background(), detailRequest)
-	if err != nil {
-		return errors.New("Error on detail query:" + err.Error())
-	}
-
-	v.OpeningHours = *detailResp.OpeningHours
-	v.Website = detailResp.Website
-	v.PhoneNumber = detailResp.InternationalPhoneNumber
-	v.OpeningHoursText = detailResp.OpeningHours.WeekdayText
-
-	return nil
-}
-
-//ListVenues gives back a slice with all venues in a folder
-func ListVenues() ([]Venue, error) {
-	var result []Venue
-	files, err := ioutil.ReadDir(datafolder)
-	if err != nil {
-		return result, errors.New("Error reading folder: " + err.Error())
+	for i, p := range v.OpeningHours.Periods {
+		field := "OpeningHours.Periods[" + strconv.Itoa(i) + "]"
+		if p.Open.Day < time.Sunday || p.Open.Day > time.Saturday || p.Close.Day < time.Sunday || p.Close.Day > time.Saturday {
+			return ValidationError{field, "day must be between 0 (Sunday) and 6 (Saturday)"}
+		}
+		if !validTime(p.Open.Time) {
+			return ValidationError{field, "opening time " + p.Open.Time + " is not in the format hhmm"}
+		}
+		if p.Close.Time != "" && !validTime(p.Close.Time) {
+			return ValidationError{field, "closing time " + p.Close.Time + " is not in the format hhmm"}
+		}
 	}
-	for _, f := range files {
-		if f.IsDir() {
-			continue
+	for i, e := range v.Exceptions {
+		field := "Exceptions[" + strconv.Itoa(i) + "]"
+		if e.From.IsZero() || e.To.IsZero() {
+			return ValidationError{field, "needs a first and a last day"}
+		}
+		if e.To.Format(layoutDate) < e.From.Format(layoutDate) {
+			return ValidationError{field, "last day must not be before the first day"}
+		}
+		if e.Closed && (e.Open != "" || e.Close != "") {
+			return ValidationError{field, "a closure has no opening hours"}
+		}
+		if (e.Open == "") != (e.Close == "") {
+			return ValidationError{field, "needs both an opening and a closing time or none"}
 		}
-		extension := filepath.Ext(f.Name())
-		if strings.Compare(extension, ".json") != 0 {
-			continue
+		if e.Open != "" && (!validTime(e.Open) || !validTime(e.Close)) {
+			return ValidationError{field, "opening and closing time must be in the format hhmm"}
 		}
-		var v Venue
-		err := v.loadfromFile(filepath.Join(datafolder, f.Name()))
+	}
+	visitids := make(map[string]bool)
+	for i, visit := range v.Visits {
+		field := "Visits[" + strconv.Itoa(i) + "]"
+		err := visit.validate(field)
 		if err != nil {
-			return result, errors.New("Error loading one venue: " + err.Error())
+			return err
+		}
+		if visit.VisitID != "" && visitids[visit.VisitID] {
+			return ValidationError{field + ".VisitID", "must be unique"}
 		}
-		result = append(result, v)
+		visitids[visit.VisitID] = true
 	}
-	return result, nil
+	return nil
+}
+
+//NewVenueID gives back a new random ID for a venue. The ID is assigned once on creation and never changes afterwards
+func NewVenueID() string {
+	return uuid.New().String()
 }
@@ -0,0 +1,113 @@
+package venue
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//Visit holds everything worth remembering about one visit of a venue
+type Visit struct {
+	VisitID   string
+	Date      time.Time
+	Attendees []string
+	//Rating of this visit from 1 to 5, 0 means the visit was not rated
+	Rating int
+	Dishes []string
+	//Cost is the total amount paid for the visit in Currency
+	Cost     float64
+	Currency string
+	Comment  string
+}
+
+//UnmarshalJSON decodes a visit. Visits used to be saved as bare timestamps, those are still accepted
+func (vi *Visit) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var t time.Time
+		err := json.Unmarshal(data, &t)
+		if err != nil {
+			return err
+		}
+		*vi = Visit{Date: t}
+		return nil
+	}
+	//plainVisit has no methods, so decoding it doesn't end up here again
+	type plainVisit Visit
+	var p plainVisit
+	err := json.Unmarshal(data, &p)
+	if err != nil {
+		return err
+	}
+	*vi = Visit(p)
+	return nil
+}
+
+//NewVisitID gives back a new random ID for a visit
+func NewVisitID() string {
+	return uuid.New().String()
+}
+
+//AssignVisitIDs gives every visit without an ID a new one and reports if there was any.
+//The visits are copied before, so other venues sharing them are not changed
+func (v *Venue) AssignVisitIDs() bool {
+	missing := false
+	for _, visit := range v.Visits {
+		if visit.VisitID == "" {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return false
+	}
+	visits := make([]Visit, len(v.Visits))
+	copy(visits, v.Visits)
+	for i := range visits {
+		if visits[i].VisitID == "" {
+			visits[i].VisitID = NewVisitID()
+		}
+	}
+	v.Visits = visits
+	return true
+}
+
+//FindVisit gives back the index of the visit with the given ID or -1 if the venue has no such visit
+func (v Venue) FindVisit(id string) int {
+	for i, visit := range v.Visits {
+		if visit.VisitID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+//LastVisit gives back the date of the most recent visit, which is zero if the venue was never visited.
+//Visits can be edited later on, so the last one in the slice is not necessarily the most recent
+func (v Venue) LastVisit() time.Time {
+	var last time.Time
+	for _, visit := range v.Visits {
+		if visit.Date.After(last) {
+			last = visit.Date
+		}
+	}
+	return last
+}
+
+//validate checks the visit, field is the name used in the ValidationError
+func (vi Visit) validate(field string) error {
+	if vi.Date.IsZero() {
+		return ValidationError{field + ".Date", "must not be empty"}
+	}
+	if vi.Rating < 0 || vi.Rating > 5 {
+		return ValidationError{field + ".Rating", "must be between 0 and 5"}
+	}
+	if vi.Cost < 0 {
+		return ValidationError{field + ".Cost", "must not be negative"}
+	}
+	if vi.Currency != "" && (len(vi.Currency) != 3 || strings.ToUpper(vi.Currency) != vi.Currency) {
+		return ValidationError{field + ".Currency", "must be a three letter currency code like EUR"}
+	}
+	return nil
+}
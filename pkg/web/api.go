@@ -2,23 +2,20 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"log"
-	"math"
-	"math/rand"
+	"mime"
 	"net/http"
-	"sort"
 	"strconv"
 	"strings"
-	"time"
-
-	"github.com/philmacfly/wheretoeat/pkg/config"
 
 	"github.com/gorilla/mux"
+	"github.com/philmacfly/wheretoeat/pkg/selection"
+	"github.com/philmacfly/wheretoeat/pkg/service"
 	"github.com/philmacfly/wheretoeat/pkg/venue"
 )
 
-var criteriaweight config.Weight
-
 func apierror(w http.ResponseWriter, r *http.Request, err string, httpcode int) {
 	log.Println(err)
 	er := errorResponse{strconv.Itoa(httpcode), err}
@@ -29,27 +26,79 @@ func apierror(w http.ResponseWriter, r *http.Request, err string, httpcode int)
 	http.Error(w, string(j), httpcode)
 }
 
+//statusCode maps the kind of an error of the service to the HTTP status code of the answer
+func statusCode(err error) int {
+	switch service.KindOf(err) {
+	case service.Invalid:
+		return http.StatusBadRequest
+	case service.NotFound:
+		return http.StatusNotFound
+	case service.Conflict:
+		return http.StatusPreconditionFailed
+	case service.PreconditionRequired:
+		return http.StatusPreconditionRequired
+	case service.Unsupported:
+		return http.StatusUnsupportedMediaType
+	}
+	return http.StatusInternalServerError
+}
+
+func etag(v venue.Venue) string {
+	return `"` + strconv.Itoa(v.Revision) + `"`
+}
+
+//precondition reads the revisions a change may be applied to from the If-Match header of the request.
+//Changing requests have to send the header
+func precondition(r *http.Request) (service.Precondition, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" {
+		return service.Precondition{}, service.Error{Kind: service.PreconditionRequired,
+			Err: errors.New("If-Match header with the ETag of the venue is required")}
+	}
+	if h == "*" {
+		return service.AnyRevision(), nil
+	}
+	var revisions []int
+	for _, t := range strings.Split(h, ",") {
+		t = strings.TrimSpace(t)
+		if !strings.HasPrefix(t, `"`) || !strings.HasSuffix(t, `"`) || len(t) < 2 {
+			if strings.HasPrefix(t, "W/") {
+				//weak ETags never match with If-Match
+				continue
+			}
+			return service.Precondition{}, service.Error{Kind: service.Invalid, Err: errors.New("Invalid ETag in If-Match header: " + t)}
+		}
+		revision, err := strconv.Atoi(t[1 : len(t)-1])
+		if err != nil {
+			//not one of our ETags, so it can't match
+			continue
+		}
+		revisions = append(revisions, revision)
+	}
+	return service.AtRevision(revisions...), nil
+}
+
+//patchFormat gives back the format of the patch sent with the request. Requests without a content type
+//and plain JSON are treated as merge patch
+func patchFormat(r *http.Request) string {
+	contenttype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || contenttype == "application/json" {
+		return service.MergePatch
+	}
+	return contenttype
+}
+
 func mainAPIHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("Main API Handler")
 }
 
 func listVenuesAPIHandler(w http.ResponseWriter, r *http.Request) {
 	sb := r.FormValue("sortby")
-	vv, err := venue.ListVenues()
+	vv, err := service.ListVenues(sb)
 	if err != nil {
-		apierror(w, r, "Error Listing Venues: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error Listing Venues: "+err.Error(), statusCode(err))
 		return
 	}
-	switch sb {
-	case "name-desc":
-		sort.Sort(venue.ByNameReverse(vv))
-	case "rating":
-		sort.Sort(venue.ByRating(vv))
-	case "rating-desc":
-		sort.Sort(venue.ByRatingReverse(vv))
-	default:
-		sort.Sort(venue.ByName(vv))
-	}
 	j, err := json.Marshal(&vv)
 	if err != nil {
 		apierror(w, r, "Error marshalling Venues: "+err.Error(), http.StatusInternalServerError)
@@ -63,11 +112,9 @@ func listVenuesAPIHandler(w http.ResponseWriter, r *http.Request) {
 func getVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	i := vars["ID"]
-	var result venue.Venue
-	result.VenueID = i
-	err := result.LoadFromDataLocation()
+	result, err := service.GetVenue(i)
 	if err != nil {
-		apierror(w, r, "Error Loading Venue File: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error Loading Venue: "+err.Error(), statusCode(err))
 		return
 	}
 	j, err := json.Marshal(&result)
@@ -77,6 +124,7 @@ func getVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(result))
 	w.Write(j)
 }
 
@@ -88,10 +136,9 @@ func postVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 		apierror(w, r, "Error decoding Venue: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	v.VenueID = v.GenerateVenueID()
-	err = v.SavetoDataLocation()
+	v, err = service.CreateVenue(v)
 	if err != nil {
-		apierror(w, r, "Error saving Venue: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error saving Venue: "+err.Error(), statusCode(err))
 		return
 	}
 	j, err := json.Marshal(&v)
@@ -101,30 +148,26 @@ func postVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(v))
 	w.Write(j)
 }
 
 func patchVenueAPIHander(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	i := vars["ID"]
-	var result venue.Venue
-	result.VenueID = i
-	err := result.LoadFromDataLocation()
+	p, err := precondition(r)
 	if err != nil {
-		apierror(w, r, "Error Loading Venue File: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error checking revision: "+err.Error(), statusCode(err))
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
-	var v venue.Venue
-	err = decoder.Decode(&v)
+	patch, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		apierror(w, r, "Error decoding Venue: "+err.Error(), http.StatusBadRequest)
+		apierror(w, r, "Error reading patch: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	v.VenueID = v.GenerateVenueID()
-	err = v.SavetoDataLocation()
+	v, err := service.PatchVenue(i, p, patchFormat(r), patch)
 	if err != nil {
-		apierror(w, r, "Error saving Venue: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error patching Venue: "+err.Error(), statusCode(err))
 		return
 	}
 	j, err := json.Marshal(&v)
@@ -134,17 +177,21 @@ func patchVenueAPIHander(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(v))
 	w.Write(j)
 }
 
 func deleteVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	i := vars["ID"]
-	var result venue.Venue
-	result.VenueID = i
-	err := result.Delete()
+	p, err := precondition(r)
 	if err != nil {
-		apierror(w, r, "Error Deleting Venue File: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error checking revision: "+err.Error(), statusCode(err))
+		return
+	}
+	err = service.DeleteVenue(i, p)
+	if err != nil {
+		apierror(w, r, "Error Deleting Venue: "+err.Error(), statusCode(err))
 		return
 	}
 }
@@ -152,24 +199,21 @@ func deleteVenueAPIHandler(w http.ResponseWriter, r *http.Request) {
 func addVisitAPIHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	i := vars["ID"]
-	var result venue.Venue
-	result.VenueID = i
-	err := result.LoadFromDataLocation()
+	decoder := json.NewDecoder(r.Body)
+	p, err := precondition(r)
 	if err != nil {
-		apierror(w, r, "Error Loading Venue File: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error checking revision: "+err.Error(), statusCode(err))
 		return
 	}
-	decoder := json.NewDecoder(r.Body)
 	var a addVisitsRequest
 	err = decoder.Decode(&a)
 	if err != nil {
 		apierror(w, r, "Error decoding Venue: "+err.Error(), http.StatusBadRequest)
 		return
 	}
-	result.Visits = append(result.Visits, a.Visits...)
-	err = result.SavetoDataLocation()
+	result, err := service.AddVisits(i, p, a.Visits)
 	if err != nil {
-		apierror(w, r, "Error saving Venue: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error saving Venue: "+err.Error(), statusCode(err))
 		return
 	}
 	j, err := json.Marshal(&result)
@@ -179,20 +223,109 @@ func addVisitAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(result))
 	w.Write(j)
 }
 
+func getVenueHistoryAPIHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	i := vars["ID"]
+	history, err := service.VenueHistory(i)
+	if err != nil {
+		apierror(w, r, "Error Loading History: "+err.Error(), statusCode(err))
+		return
+	}
+	j, err := json.Marshal(&history)
+	if err != nil {
+		apierror(w, r, "Error marshalling History: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func getVisitAPIHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	i := vars["ID"]
+	visitid := vars["VisitID"]
+	visit, v, err := service.GetVisit(i, visitid)
+	if err != nil {
+		apierror(w, r, "Error Loading Visit: "+err.Error(), statusCode(err))
+		return
+	}
+	j, err := json.Marshal(&visit)
+	if err != nil {
+		apierror(w, r, "Error marshalling Visit: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(v))
+	w.Write(j)
+}
+
+func patchVisitAPIHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	i := vars["ID"]
+	visitid := vars["VisitID"]
+	p, err := precondition(r)
+	if err != nil {
+		apierror(w, r, "Error checking revision: "+err.Error(), statusCode(err))
+		return
+	}
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		apierror(w, r, "Error reading patch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	visit, v, err := service.PatchVisit(i, visitid, p, patchFormat(r), patch)
+	if err != nil {
+		apierror(w, r, "Error patching Visit: "+err.Error(), statusCode(err))
+		return
+	}
+	j, err := json.Marshal(&visit)
+	if err != nil {
+		apierror(w, r, "Error marshalling Visit: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(v))
+	w.Write(j)
+}
+
+func deleteVisitAPIHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	i := vars["ID"]
+	visitid := vars["VisitID"]
+	p, err := precondition(r)
+	if err != nil {
+		apierror(w, r, "Error checking revision: "+err.Error(), statusCode(err))
+		return
+	}
+	v, err := service.DeleteVisit(i, visitid, p)
+	if err != nil {
+		apierror(w, r, "Error Deleting Visit: "+err.Error(), statusCode(err))
+		return
+	}
+	w.Header().Set("ETag", etag(v))
+}
+
 func getVenueFromPlacesAPIHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	q := vars["query"]
-	v, err := venue.GetVenubyPlaceSearch(q)
+	cc, err := service.SearchPlaces(q)
 	if err != nil {
-		apierror(w, r, "Error searching places api: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error searching places api: "+err.Error(), statusCode(err))
 		return
 	}
-	j, err := json.Marshal(&v)
+	if cc == nil {
+		cc = []venue.PlaceCandidate{}
+	}
+	j, err := json.Marshal(&cc)
 	if err != nil {
-		apierror(w, r, "Error marshalling Venue: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error marshalling Candidates: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -200,19 +333,20 @@ func getVenueFromPlacesAPIHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(j)
 }
 
-func getNotVisitedVenue(w http.ResponseWriter, r *http.Request) {
-	vv, err := venue.ListVenues()
+func postVenueFromPlaceAPIHandler(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var c venue.PlaceCandidate
+	err := decoder.Decode(&c)
 	if err != nil {
-		apierror(w, r, "Error Listing Venues: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error decoding Candidate: "+err.Error(), http.StatusBadRequest)
+		return
 	}
-	var oo []venue.Venue
-	for _, v := range vv {
-		if len(v.Visits) < 1 {
-			oo = append(oo, v)
-		}
+	v, err := service.VenueFromPlace(c)
+	if err != nil {
+		apierror(w, r, "Error getting details from places api: "+err.Error(), statusCode(err))
+		return
 	}
-	o := oo[rand.Intn(len(oo))]
-	j, err := json.Marshal(&o)
+	j, err := json.Marshal(&v)
 	if err != nil {
 		apierror(w, r, "Error marshalling Venue: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -222,65 +356,92 @@ func getNotVisitedVenue(w http.ResponseWriter, r *http.Request) {
 	w.Write(j)
 }
 
-func getWeightedArray(venues []venue.Venue) []venue.Venue {
-	var res []venue.Venue
-
-	for _, v := range venues {
-		lastvisit := 356
-		daycount := 1
-		rating := v.Rating
-		if len(v.Visits) > 0 {
-			lv := v.Visits[len(v.Visits)-1]
-			dur := time.Now().Sub(lv)
-			lastvisit = int(dur / (time.Hour * 24))
-			if lastvisit < 0 {
-				lastvisit = 1
-			}
-			daycount = len(v.Visits)
-		}
-		lastvisitf := float64(lastvisit) * criteriaweight.LastVisit
-		daycountf := float64(daycount) * criteriaweight.DayCount
-		ratingf := float64(rating) * criteriaweight.Rating
-		ticketcount := int(math.Ceil((lastvisitf / daycountf) * ratingf))
-		for i := 0; i < ticketcount; i++ {
-			res = append(res, v)
-		}
+func getNotVisitedVenue(w http.ResponseWriter, r *http.Request) {
+	includeclosed := r.FormValue("includeclosed") != ""
+	o, err := service.PickNotVisited(includeclosed)
+	if err != nil {
+		apierror(w, r, "Error picking Venue: "+err.Error(), statusCode(err))
+		return
+	}
+	j, err := json.Marshal(&o)
+	if err != nil {
+		apierror(w, r, "Error marshalling Venue: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	return res
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
 }
 
 func getNextVenuetoVisit(w http.ResponseWriter, r *http.Request) {
 	new := !(strings.ToLower(r.FormValue("new")) == "")
 	old := !(strings.ToLower(r.FormValue("old")) == "")
 	weighted := !(strings.ToLower(r.FormValue("weighted")) == "")
-	vv, err := venue.ListVenues()
+	includeclosed := r.FormValue("includeclosed") != ""
+	o := service.PickOptions{Old: old, New: new, Strategy: r.FormValue("strategy"), IncludeClosed: includeclosed}
+	//weighted is kept for clients from before the strategies
+	if o.Strategy == "" && weighted {
+		o.Strategy = selection.WeightedName
+	}
+	if r.FormValue("anytime") == "" {
+		at, err := service.ParseAt(r.FormValue("at"))
+		if err != nil {
+			apierror(w, r, "Error picking Venue: "+err.Error(), statusCode(err))
+			return
+		}
+		o.At = at
+	}
+	res, err := service.ExplainNext(o)
 	if err != nil {
-		apierror(w, r, "Error Listing Venues: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error picking Venue: "+err.Error(), statusCode(err))
 		return
 	}
-	var candiates []venue.Venue
 
-	for _, v := range vv {
-		if ((len(v.Visits) > 0) && old) || ((len(v.Visits) == 0) && new) {
-			candiates = append(candiates, v)
-		}
+	//explain gives back the chance of every candidate next to the venue
+	var j []byte
+	if r.FormValue("explain") != "" {
+		j, err = json.Marshal(&res)
+	} else {
+		j, err = json.Marshal(&res.Venue)
 	}
-
-	if len(candiates) < 1 {
-		apierror(w, r, "No candidates to choose from", http.StatusInternalServerError)
+	if err != nil {
+		apierror(w, r, "Error marshalling Venue: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if weighted {
-		candiates = getWeightedArray(candiates)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
+
+func postUpdatefromPlaces(w http.ResponseWriter, r *http.Request) {
+	report, err := service.UpdateFromPlaces()
+	if err != nil {
+		apierror(w, r, "Error Updating Venues: "+err.Error(), statusCode(err))
+		return
+	}
+	j, err := json.Marshal(&report)
+	if err != nil {
+		apierror(w, r, "Error marshalling Report: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	c := candiates[rand.Intn(len(candiates))]
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
+}
 
-	j, err := json.Marshal(&c)
+func getHealthAPIHandler(w http.ResponseWriter, r *http.Request) {
+	problems, err := service.Problems()
 	if err != nil {
-		apierror(w, r, "Error marshalling Venue: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error Listing Venues: "+err.Error(), statusCode(err))
+		return
+	}
+	h := healthResponse{Status: "ok", Problems: problems}
+	if len(h.Problems) > 0 {
+		h.Status = "degraded"
+	}
+	j, err := json.Marshal(&h)
+	if err != nil {
+		apierror(w, r, "Error marshalling Health: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -288,47 +449,42 @@ func getNextVenuetoVisit(w http.ResponseWriter, r *http.Request) {
 	w.Write(j)
 }
 
-func postUpdatefromPlaces(w http.ResponseWriter, r *http.Request) {
-	vv, err := venue.ListVenues()
+func getPlaceCacheAPIHandler(w http.ResponseWriter, r *http.Request) {
+	stats, err := service.PlaceCacheStats()
 	if err != nil {
-		apierror(w, r, "Error Listing Venues: "+err.Error(), http.StatusInternalServerError)
+		apierror(w, r, "Error Loading Cache Statistics: "+err.Error(), http.StatusNotFound)
 		return
 	}
-	for _, v := range vv {
-		if v.GooglePlaceID == "" {
-			continue
-		}
-		err := v.UpdateInfos()
-		if err != nil {
-			apierror(w, r, "Error Updating Venue: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		err = v.SavetoDataLocation()
-		if err != nil {
-			apierror(w, r, "Error Saving Venue: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
+	j, err := json.Marshal(&stats)
+	if err != nil {
+		apierror(w, r, "Error marshalling Cache Statistics: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
-}
 
-//SetWeights gets the criteria Weight from the config to save them for calulations later
-func SetWeights(w config.Weight) {
-	criteriaweight = w
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(j)
 }
 
 func getAPIRouter(prefix string) *mux.Router {
 	r := mux.NewRouter().PathPrefix(prefix).Subrouter()
 	r.HandleFunc("/", mainAPIHandler)
+	r.HandleFunc("/admin/health", getHealthAPIHandler).Methods("GET")
+	r.HandleFunc("/admin/placecache", getPlaceCacheAPIHandler).Methods("GET")
 	r.HandleFunc("/venue", postVenueAPIHandler).Methods("POST")
 	r.HandleFunc("/venue/list", listVenuesAPIHandler).Methods("GET")
 	r.HandleFunc("/venue/notvisited", getNotVisitedVenue).Methods("GET")
 	r.HandleFunc("/venue/next", getNextVenuetoVisit)
 	r.HandleFunc("/venue/updatefromplaces", postUpdatefromPlaces).Methods("POST")
 	r.HandleFunc("/venue/getfromplaces/{query}", getVenueFromPlacesAPIHandler).Methods("GET")
+	r.HandleFunc("/venue/fromplace", postVenueFromPlaceAPIHandler).Methods("POST")
 	r.HandleFunc("/venue/{ID}", getVenueAPIHandler).Methods("GET")
 	r.HandleFunc("/venue/{ID}", patchVenueAPIHander).Methods("PATCH")
 	r.HandleFunc("/venue/{ID}", deleteVenueAPIHandler).Methods("DELETE")
 	r.HandleFunc("/venue/{ID}/addvisits", addVisitAPIHandler).Methods("POST")
+	r.HandleFunc("/venue/{ID}/history", getVenueHistoryAPIHandler).Methods("GET")
+	r.HandleFunc("/venue/{ID}/visits/{VisitID}", getVisitAPIHandler).Methods("GET")
+	r.HandleFunc("/venue/{ID}/visits/{VisitID}", patchVisitAPIHandler).Methods("PATCH")
+	r.HandleFunc("/venue/{ID}/visits/{VisitID}", deleteVisitAPIHandler).Methods("DELETE")
 
 	return r
 }
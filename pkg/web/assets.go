@@ -0,0 +1,79 @@
+package web
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	webassets "github.com/philmacfly/wheretoeat/web"
+)
+
+//assets holds the templates and static files, embedded ones can be overridden by a theme folder
+var assets fs.FS = webassets.Files
+
+//templates holds every template parsed at startup by its path inside the templates folder
+var templates map[string]*template.Template
+
+//overlayFS opens files from the theme folder first and falls back to the embedded files
+type overlayFS struct {
+	theme fs.FS
+	base  fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.theme.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+//SetupAssets parses all templates once. If themefolder is set, files in its templates and static subfolders
+//are used instead of the embedded ones with the same path. It has to be called before SetupRouters
+func SetupAssets(themefolder string) error {
+	a := fs.FS(webassets.Files)
+	if themefolder != "" {
+		info, err := os.Stat(themefolder)
+		if err != nil {
+			return errors.New("Error opening theme folder: " + err.Error())
+		}
+		if !info.IsDir() {
+			return errors.New("Theme folder " + themefolder + " is no folder")
+		}
+		a = overlayFS{theme: os.DirFS(themefolder), base: webassets.Files}
+	}
+
+	parsed := make(map[string]*template.Template)
+	err := fs.WalkDir(webassets.Files, "templates", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".html" {
+			return nil
+		}
+		t, err := template.ParseFS(a, p)
+		if err != nil {
+			return errors.New("Error parsing template " + p + ": " + err.Error())
+		}
+		parsed[strings.TrimPrefix(p, "templates/")] = t
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	assets = a
+	templates = parsed
+	return nil
+}
+
+func staticFiles() fs.FS {
+	static, err := fs.Sub(assets, "static")
+	if err != nil {
+		//static is a valid path, so Sub can't fail
+		panic(err)
+	}
+	return static
+}
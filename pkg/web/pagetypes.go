@@ -2,10 +2,13 @@ package web
 
 import (
 	"errors"
+	"fmt"
 	"html/template"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/philmacfly/wheretoeat/pkg/service"
 	"github.com/philmacfly/wheretoeat/pkg/venue"
 	"googlemaps.github.io/maps"
 )
@@ -19,8 +22,9 @@ type defaultPage struct {
 }
 
 type mainPage struct {
-	Default defaultPage
-	Venues  []webVenue
+	Default      defaultPage
+	Venues       []webVenue
+	ShowArchived bool
 }
 
 type webOpeningHours struct {
@@ -33,134 +37,420 @@ type webOpeningHours struct {
 	Sunday    string
 }
 
+const minutesPerDay = 24 * 60
+
+//closedMarker and allDayMarker are written instead of periods for days closed or open around the clock
+const (
+	closedMarker = "closed"
+	allDayMarker = "24h"
+)
+
+//days gives back the opening hours of every day indexed by time.Weekday
+func (wo *webOpeningHours) days() []*string {
+	return []*string{&wo.Sunday, &wo.Monday, &wo.Tuesday, &wo.Wednesday, &wo.Thursday, &wo.Friday, &wo.Saturday}
+}
+
 type webVenue struct {
 	VenueID       string
+	Revision      int
 	Name          string
 	Address       string
 	Rating        int
-	GooglePlaceID string
+	PlaceProvider string
+	PlaceID       string
 	OpeningHours  webOpeningHours
 	Website       string
 	PhoneNumber   string
 	Notes         string
-	Visits        []time.Time
+	Visits        []venue.Visit
+	VisitLog      []webVisit
 	LastVisit     string
+	LastRefresh   string
+	//Stale is set if the infos of a venue with a PlaceID were not refreshed for too long
+	Stale bool
+	//BusinessStatus is set to a readable text if the venue is closed
+	BusinessStatus   string
+	Archived         bool
+	ClosedOnHolidays bool
+	Exceptions       []webException
+	//Today tells about an exception or public holiday today
+	Today string
+}
+
+//webException is an exception of the opening hours formatted for the view page, Index is its position in the venue
+type webException struct {
+	Index  int
+	From   string
+	To     string
+	Hours  string
+	Reason string
+}
+
+func convertExceptiontoWebException(index int, e venue.Exception) webException {
+	result := webException{Index: index, From: e.From.Format(layoutISO), To: e.To.Format(layoutISO), Reason: e.Reason}
+	switch {
+	case e.Closed:
+		result.Hours = "Closed"
+	case e.Open == "":
+		result.Hours = "Open all day"
+	default:
+		result.Hours = "Open " + formatClock(e.Open) + "-" + formatClock(e.Close)
+	}
+	return result
+}
+
+//convertFormtoException reads the exception form. Without a last day the exception lasts one day, an opening
+//without hours lasts the whole day
+func convertFormtoException(from string, to string, kind string, hours string, reason string) (venue.Exception, error) {
+	result := venue.Exception{Closed: kind != "open", Reason: strings.TrimSpace(reason)}
+	var err error
+	result.From, err = time.Parse(layoutISO, from)
+	if err != nil {
+		return result, errors.New("Error parsing first day: " + err.Error())
+	}
+	result.To = result.From
+	if to != "" {
+		result.To, err = time.Parse(layoutISO, to)
+		if err != nil {
+			return result, errors.New("Error parsing last day: " + err.Error())
+		}
+	}
+	hours = strings.TrimSpace(hours)
+	if !result.Closed && hours != "" {
+		ocs := strings.Split(strings.Replace(hours, "–", "-", -1), "-")
+		if len(ocs) != 2 {
+			return result, errors.New(hours + " is not a period like 11:30-14:00")
+		}
+		open, err := parseClock(ocs[0])
+		if err != nil || open == minutesPerDay {
+			return result, errors.New(strings.TrimSpace(ocs[0]) + " is not a valid opening time")
+		}
+		close, err := parseClock(ocs[1])
+		if err != nil {
+			return result, errors.New(strings.TrimSpace(ocs[1]) + " is not a valid closing time")
+		}
+		result.Open = clock(open)
+		result.Close = clock(close % minutesPerDay)
+	}
+	return result, nil
+}
+
+//formValues gives back the editable fields of the venue as the values of the venue form, so they can be sent again
+func (wv webVenue) formValues() map[string]string {
+	return map[string]string{"Name": wv.Name, "Address": wv.Address, "Rating": strconv.Itoa(wv.Rating),
+		"placeprovider": wv.PlaceProvider, "placesid": wv.PlaceID, "Website": wv.Website, "phone": wv.PhoneNumber, "Notes": wv.Notes,
+		"closedonholidays": checkbox(wv.ClosedOnHolidays), "Monday": wv.OpeningHours.Monday, "Tuesday": wv.OpeningHours.Tuesday, "Wednesday": wv.OpeningHours.Wednesday,
+		"Thursday": wv.OpeningHours.Thursday, "Friday": wv.OpeningHours.Friday, "Saturday": wv.OpeningHours.Saturday,
+		"Sunday": wv.OpeningHours.Sunday}
+}
+
+//checkbox gives back the value a checked checkbox is sent with
+func checkbox(checked bool) string {
+	if checked {
+		return "on"
+	}
+	return ""
+}
+
+//venueChanges lists the editable fields which differ between the two venues
+func venueChanges(yours webVenue, current webVenue) []conflictChange {
+	var result []conflictChange
+	y := yours.formValues()
+	c := current.formValues()
+	for _, field := range []string{"Name", "Address", "Rating", "placeprovider", "placesid", "Website", "phone", "Notes",
+		"closedonholidays", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"} {
+		if y[field] != c[field] {
+			result = append(result, conflictChange{Field: field, Yours: y[field]})
+		}
+	}
+	return result
+}
+
+//webVisit is a visit formatted for the visit log and the visit form
+type webVisit struct {
+	VisitID   string
+	Date      string
+	Attendees string
+	Rating    int
+	Dishes    string
+	Cost      string
+	Currency  string
+	Comment   string
+}
+
+func convertVisittoWebVisit(v venue.Visit) webVisit {
+	result := webVisit{VisitID: v.VisitID, Date: v.Date.Format(layoutISO), Attendees: strings.Join(v.Attendees, ", "),
+		Rating: v.Rating, Dishes: strings.Join(v.Dishes, ", "), Currency: v.Currency, Comment: v.Comment}
+	if v.Cost != 0 {
+		result.Cost = strconv.FormatFloat(v.Cost, 'f', 2, 64)
+	}
+	return result
+}
+
+//webChange is a change of the history of a venue formatted for its timeline
+type webChange struct {
+	Time   string
+	Field  string
+	Old    string
+	New    string
+	Source string
+}
+
+//convertHistorytoTimeline formats the history with the newest change first
+func convertHistorytoTimeline(history []venue.Change) []webChange {
+	var result []webChange
+	for i := len(history) - 1; i >= 0; i-- {
+		c := history[i]
+		result = append(result, webChange{Time: c.Time.Format(layoutISO + " 15:04"), Field: c.Field, Old: c.Old,
+			New: c.New, Source: c.Source})
+	}
+	return result
+}
+
+func convertWebVisittoVisit(wv webVisit) (venue.Visit, error) {
+	result := venue.Visit{VisitID: wv.VisitID, Attendees: splitList(wv.Attendees), Rating: wv.Rating,
+		Dishes: splitList(wv.Dishes), Currency: strings.ToUpper(strings.TrimSpace(wv.Currency)), Comment: wv.Comment}
+	d, err := time.Parse(layoutISO, wv.Date)
+	if err != nil {
+		return result, errors.New("Error parsing given date: " + err.Error())
+	}
+	result.Date = d
+	if wv.Cost != "" {
+		result.Cost, err = strconv.ParseFloat(strings.Replace(wv.Cost, ",", ".", 1), 64)
+		if err != nil {
+			return result, errors.New("Error parsing given cost: " + err.Error())
+		}
+	}
+	return result, nil
+}
+
+//formValues gives back the visit as the values of the visit form, so it can be sent again
+func (wv webVisit) formValues() map[string]string {
+	return map[string]string{"visitid": wv.VisitID, "date": wv.Date, "attendees": wv.Attendees,
+		"rating": strconv.Itoa(wv.Rating), "dishes": wv.Dishes, "cost": wv.Cost, "currency": wv.Currency,
+		"comment": wv.Comment}
+}
+
+//splitList splits a comma separated form value into its trimmed, non-empty items
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
 }
 
 func convertVenuetoWebVenue(v venue.Venue) webVenue {
-	result := webVenue{VenueID: v.VenueID, Name: v.Name, Address: v.Address,
-		Rating: v.Rating, GooglePlaceID: v.GooglePlaceID, Website: v.Website,
+	result := webVenue{VenueID: v.VenueID, Revision: v.Revision, Name: v.Name, Address: v.Address,
+		Rating: v.Rating, PlaceProvider: v.PlaceProvider, PlaceID: v.PlaceID, Website: v.Website,
 		PhoneNumber: v.PhoneNumber, Notes: v.Notes, Visits: v.Visits}
 
-	for _, value := range v.OpeningHours.Periods {
-		time := value.Open.Time + "-" + value.Close.Time + ";"
-		switch value.Open.Day {
-		case 0:
-			result.OpeningHours.Sunday = result.OpeningHours.Sunday + time
-		case 1:
-			result.OpeningHours.Monday = result.OpeningHours.Monday + time
-		case 2:
-			result.OpeningHours.Tuesday = result.OpeningHours.Tuesday + time
-		case 3:
-			result.OpeningHours.Wednesday = result.OpeningHours.Wednesday + time
-		case 4:
-			result.OpeningHours.Thursday = result.OpeningHours.Thursday + time
-		case 5:
-			result.OpeningHours.Friday = result.OpeningHours.Friday + time
-		case 6:
-			result.OpeningHours.Saturday = result.OpeningHours.Saturday + time
+	result.OpeningHours = convertPeriodstoWebOpeningHours(v.OpeningHours.Periods, v.ClosedAllWeek)
+	if v.PlaceID != "" {
+		result.LastRefresh = "never"
+		if !v.LastRefresh.IsZero() {
+			result.LastRefresh = v.LastRefresh.Format(layoutISO + " 15:04")
+		}
+		result.Stale = time.Since(v.LastRefresh) > service.StaleAfter()
+	}
+	switch v.BusinessStatus {
+	case venue.BusinessClosedTemporarily:
+		result.BusinessStatus = "Closed temporarily"
+	case venue.BusinessClosedPermanently:
+		result.BusinessStatus = "Closed permanently"
+	}
+	result.Archived = v.Archived
+	result.ClosedOnHolidays = v.ClosedOnHolidays
+	for i, e := range v.Exceptions {
+		result.Exceptions = append(result.Exceptions, convertExceptiontoWebException(i, e))
+	}
+	now := service.Now()
+	if e, ok := v.ExceptionOn(now); ok {
+		result.Today = "Today: " + convertExceptiontoWebException(0, e).Hours
+		if e.Reason != "" {
+			result.Today += " (" + e.Reason + ")"
 		}
+	} else if h, ok := venue.HolidayOn(now); ok && v.ClosedOnHolidays {
+		result.Today = "Today: Closed for " + h.Name
 	}
 	result.LastVisit = ""
 	if len(v.Visits) > 0 {
-		result.LastVisit = v.Visits[len(v.Visits)-1].Format(layoutISO)
+		result.LastVisit = v.LastVisit().Format(layoutISO)
+	}
+	//newest visits first
+	for i := len(v.Visits) - 1; i >= 0; i-- {
+		result.VisitLog = append(result.VisitLog, convertVisittoWebVisit(v.Visits[i]))
 	}
 
 	return result
 }
 
-func convertOpeningHours(day time.Weekday, hours string) (maps.OpeningHoursPeriod, error) {
-	var result maps.OpeningHoursPeriod
+//convertPeriodstoWebOpeningHours writes the periods of every day like "11:30-14:00; 17:00-23:00". Periods
+//spanning midnight are shown on the day they start, days without periods are closed unless there are no periods at
+//all and the venue isn't closed all week
+func convertPeriodstoWebOpeningHours(pp []maps.OpeningHoursPeriod, closedallweek bool) webOpeningHours {
+	var result webOpeningHours
+	if len(pp) == 0 && !closedallweek {
+		return result
+	}
+	spans := make([][]string, 7)
+	for _, p := range pp {
+		if p.Open.Day < time.Sunday || p.Open.Day > time.Saturday {
+			continue
+		}
+		if p.Close.Time == "" {
+			//a period without closing time is open around the clock for the whole week
+			for d := range spans {
+				spans[d] = []string{allDayMarker}
+			}
+			break
+		}
+		nextday := p.Close.Day == (p.Open.Day+1)%7
+		if p.Open.Time == "0000" && p.Close.Time == "0000" && nextday {
+			spans[p.Open.Day] = append(spans[p.Open.Day], allDayMarker)
+			continue
+		}
+		close := formatClock(p.Close.Time)
+		if p.Close.Time == "0000" && nextday {
+			close = "24:00"
+		}
+		spans[p.Open.Day] = append(spans[p.Open.Day], formatClock(p.Open.Time)+"-"+close)
+	}
+	days := result.days()
+	for d := range days {
+		*days[d] = closedMarker
+		if len(spans[d]) > 0 {
+			*days[d] = strings.Join(spans[d], "; ")
+		}
+	}
+	return result
+}
 
-	input := strings.Replace(hours, " ", "", -1)
-	input = strings.Replace(input, ":", "", -1)
-	if hours == "" {
+//convertOpeningHours reads the opening hours of a day like "11:30-14:00; 17:00-23:00", "closed" or "24h".
+//A period closing before it opens spans midnight, the errors name the day they were found on
+func convertOpeningHours(day time.Weekday, hours string) ([]maps.OpeningHoursPeriod, error) {
+	var result []maps.OpeningHoursPeriod
+	hours = strings.ToLower(strings.TrimSpace(hours))
+	switch hours {
+	case "", closedMarker:
 		return result, nil
+	case allDayMarker:
+		return append(result, dayPeriod(day, 0, minutesPerDay)), nil
 	}
-	periods := strings.Split(input, ";")
-	if len(periods) < 1 {
-		return result, errors.New("No valid periods found. Watch the formatting")
-	}
-	for _, p := range periods {
-		if p == "" {
-			break
+	for _, span := range strings.FieldsFunc(hours, func(r rune) bool { return r == ';' || r == ',' }) {
+		span = strings.TrimSpace(span)
+		if span == "" {
+			continue
 		}
-		ocs := strings.Split(p, "-")
-		if len(ocs) != 2 {
-			return result, errors.New("No valid opening/closing found. Watch the formatting")
+		ocs := strings.Split(strings.Replace(span, "–", "-", -1), "-")
+		if len(ocs) != 2 || strings.TrimSpace(ocs[0]) == "" || strings.TrimSpace(ocs[1]) == "" {
+			return nil, venue.ValidationError{Field: day.String(), Message: span + " is not a period like 11:30-14:00"}
+		}
+		open, err := parseClock(ocs[0])
+		if err != nil || open == minutesPerDay {
+			return nil, venue.ValidationError{Field: day.String(), Message: strings.TrimSpace(ocs[0]) + " is not a valid opening time"}
+		}
+		close, err := parseClock(ocs[1])
+		if err != nil {
+			return nil, venue.ValidationError{Field: day.String(), Message: strings.TrimSpace(ocs[1]) + " is not a valid closing time"}
 		}
-		result.Open.Day = day
-		result.Open.Time = ocs[0]
-		result.Close.Day = day
-		result.Close.Time = ocs[1]
+		if open == close {
+			return nil, venue.ValidationError{Field: day.String(), Message: span + " opens and closes at the same time"}
+		}
+		if close < open {
+			close += minutesPerDay
+		}
+		result = append(result, dayPeriod(day, open, close))
 	}
 	return result, nil
 }
 
-func convertWebVenuetoVenue(wv webVenue) (venue.Venue, error) {
-	result := venue.Venue{VenueID: wv.VenueID, Name: wv.Name, Address: wv.Address,
-		Rating: wv.Rating, GooglePlaceID: wv.GooglePlaceID, Website: wv.Website,
-		PhoneNumber: wv.PhoneNumber, Notes: wv.Notes, Visits: wv.Visits}
-	var ocs []maps.OpeningHoursPeriod
-	oc, err := convertOpeningHours(time.Monday, wv.OpeningHours.Monday)
-	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+//dayPeriod builds the period opening on day, open and close are minutes since midnight of that day
+func dayPeriod(day time.Weekday, open int, close int) maps.OpeningHoursPeriod {
+	return maps.OpeningHoursPeriod{
+		Open:  maps.OpeningHoursOpenClose{Day: day, Time: clock(open)},
+		Close: maps.OpeningHoursOpenClose{Day: (day + time.Weekday(close/minutesPerDay)) % 7, Time: clock(close % minutesPerDay)},
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Tuesday, wv.OpeningHours.Tuesday)
-	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+}
+
+//parseClock reads a time like 9:30, 0930 or 17 as minutes since midnight, 24:00 is the end of the day
+func parseClock(s string) (int, error) {
+	s = strings.Replace(strings.TrimSpace(s), ":", "", -1)
+	if len(s) <= 2 {
+		s += "00"
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Wednesday, wv.OpeningHours.Wednesday)
-	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+	if len(s) == 3 {
+		s = "0" + s
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Thursday, wv.OpeningHours.Thursday)
-	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+	if len(s) != 4 {
+		return 0, errors.New("Invalid time " + s)
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Friday, wv.OpeningHours.Friday)
+	hh, err := strconv.Atoi(s[:2])
 	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+		return 0, err
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Saturday, wv.OpeningHours.Saturday)
+	mm, err := strconv.Atoi(s[2:])
 	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+		return 0, err
 	}
-	ocs = append(ocs, oc)
-	oc, err = convertOpeningHours(time.Sunday, wv.OpeningHours.Sunday)
-	if err != nil {
-		return result, errors.New("Error converting Opening Hours:" + err.Error())
+	if hh < 0 || mm < 0 || mm > 59 || hh > 24 || (hh == 24 && mm != 0) {
+		return 0, errors.New("Invalid time " + s)
 	}
-	ocs = append(ocs, oc)
-	for _, o := range ocs {
-		if o.Open.Time != "" {
-			result.OpeningHours.Periods = append(result.OpeningHours.Periods, o)
+	return hh*60 + mm, nil
+}
+
+//clock writes minutes since midnight like 0930
+func clock(minutes int) string {
+	return fmt.Sprintf("%02d%02d", minutes/60, minutes%60)
+}
+
+//formatClock writes a time like 0930 as 09:30
+func formatClock(t string) string {
+	if len(t) != 4 {
+		return t
+	}
+	return t[:2] + ":" + t[2:]
+}
+
+func convertWebVenuetoVenue(wv webVenue) (venue.Venue, error) {
+	result := venue.Venue{VenueID: wv.VenueID, Revision: wv.Revision, Name: wv.Name, Address: wv.Address,
+		Rating: wv.Rating, PlaceProvider: wv.PlaceProvider, PlaceID: wv.PlaceID, Website: wv.Website,
+		PhoneNumber: wv.PhoneNumber, Notes: wv.Notes, Visits: wv.Visits, ClosedOnHolidays: wv.ClosedOnHolidays}
+	days := wv.OpeningHours.days()
+	closed := false
+	//the week is read starting on Monday like it is shown, so the first error is the one on top of the form
+	for i := 1; i <= 7; i++ {
+		day := time.Weekday(i % 7)
+		pp, err := convertOpeningHours(day, *days[day])
+		if err != nil {
+			return result, errors.New("Error converting Opening Hours: " + err.Error())
 		}
+		result.OpeningHours.Periods = append(result.OpeningHours.Periods, pp...)
+		closed = closed || strings.EqualFold(strings.TrimSpace(*days[day]), closedMarker)
 	}
+	//a week with closed days but no periods is known to be closed, empty days alone mean unknown opening hours
+	result.ClosedAllWeek = closed && len(result.OpeningHours.Periods) == 0
 	return result, nil
 }
 
 type venueViewPage struct {
 	Default defaultPage
 	Venue   webVenue
+	History []webChange
+	//Holidays lists the next public holidays like "2020-12-25 Christmas Day"
+	Holidays []string
 }
 
 type venueAddPage struct {
+	Default    defaultPage
+	Venue      webVenue
+	Candidates []venue.PlaceCandidate
+}
+
+type venueEditPage struct {
 	Default defaultPage
 	Venue   webVenue
 }
@@ -168,12 +458,44 @@ type venueAddPage struct {
 type venueAddVisitPage struct {
 	Default defaultPage
 	Venue   webVenue
+	Visit   webVisit
+	Action  string
+}
+
+//conflictChange is a change of the user that could not be applied as the venue was changed in the meantime
+type conflictChange struct {
+	Field string
+	Yours string
+}
+
+type venueConflictPage struct {
+	Default defaultPage
+	Venue   webVenue
+	Changes []conflictChange
+	Action  string
+	Values  map[string]string
 }
 
 type updateDonePage struct {
 	Default defaultPage
+	Report  service.RefreshReport
 }
 
 type nextOptionsPage struct {
-	Default defaultPage
+	Default    defaultPage
+	Strategies []string
+	Strategy   string
+}
+
+//newNextOptionsPage lists the strategies with the given one or the default strategy selected
+func newNextOptionsPage(strategy string) nextOptionsPage {
+	var nop nextOptionsPage
+	nop.Default.Navbar = buildNavbar(nextVisitedActive)
+	nop.Default.Pagename = "Select next options"
+	nop.Strategies = service.Strategies()
+	nop.Strategy = strategy
+	if nop.Strategy == "" {
+		nop.Strategy = service.DefaultStrategy()
+	}
+	return nop
 }
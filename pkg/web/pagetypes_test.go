@@ -0,0 +1,122 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"googlemaps.github.io/maps"
+)
+
+func period(openday time.Weekday, open string, closeday time.Weekday, close string) maps.OpeningHoursPeriod {
+	return maps.OpeningHoursPeriod{
+		Open:  maps.OpeningHoursOpenClose{Day: openday, Time: open},
+		Close: maps.OpeningHoursOpenClose{Day: closeday, Time: close},
+	}
+}
+
+func TestConvertOpeningHours(t *testing.T) {
+	tests := []struct {
+		name  string
+		day   time.Weekday
+		hours string
+		want  []maps.OpeningHoursPeriod
+	}{
+		{"empty", time.Monday, "", nil},
+		{"closed", time.Monday, " Closed ", nil},
+		{"one period", time.Monday, "11:30-14:00", []maps.OpeningHoursPeriod{period(time.Monday, "1130", time.Monday, "1400")}},
+		{"two periods", time.Monday, "11:30-14:00; 17:00-23:00", []maps.OpeningHoursPeriod{
+			period(time.Monday, "1130", time.Monday, "1400"), period(time.Monday, "1700", time.Monday, "2300")}},
+		{"comma and dash", time.Tuesday, "11:30–14:00, 17:00 - 23:00", []maps.OpeningHoursPeriod{
+			period(time.Tuesday, "1130", time.Tuesday, "1400"), period(time.Tuesday, "1700", time.Tuesday, "2300")}},
+		{"short times", time.Wednesday, "9-17", []maps.OpeningHoursPeriod{period(time.Wednesday, "0900", time.Wednesday, "1700")}},
+		{"times without colon", time.Wednesday, "930-1700", []maps.OpeningHoursPeriod{
+			period(time.Wednesday, "0930", time.Wednesday, "1700")}},
+		{"until midnight", time.Thursday, "17:00-24:00", []maps.OpeningHoursPeriod{period(time.Thursday, "1700", time.Friday, "0000")}},
+		{"overnight", time.Friday, "22:00-02:00", []maps.OpeningHoursPeriod{period(time.Friday, "2200", time.Saturday, "0200")}},
+		{"overnight on Saturday", time.Saturday, "22:00-02:00", []maps.OpeningHoursPeriod{
+			period(time.Saturday, "2200", time.Sunday, "0200")}},
+		{"24h on Sunday", time.Sunday, "24h", []maps.OpeningHoursPeriod{period(time.Sunday, "0000", time.Monday, "0000")}},
+		{"24h on Saturday", time.Saturday, "24H", []maps.OpeningHoursPeriod{period(time.Saturday, "0000", time.Sunday, "0000")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pp, err := convertOpeningHours(tt.day, tt.hours)
+			if err != nil {
+				t.Fatalf("convertOpeningHours(%s, %q) failed: %v", tt.day, tt.hours, err)
+			}
+			if !reflect.DeepEqual(pp, tt.want) {
+				t.Errorf("convertOpeningHours(%s, %q) = %+v, want %+v", tt.day, tt.hours, pp, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertOpeningHoursErrors(t *testing.T) {
+	for _, hours := range []string{"11-", "-14", "11:30", "11:30-14:00-17:00", "abc-14", "25:00-26:00", "11:60-12:00",
+		"24:00-02:00", "24:30-02:00", "11:00-11:00", "11:30-14:00; 17"} {
+		if pp, err := convertOpeningHours(time.Monday, hours); err == nil {
+			t.Errorf("convertOpeningHours(%q) = %+v, want an error", hours, pp)
+		}
+	}
+}
+
+func TestOpeningHoursRoundTrip(t *testing.T) {
+	closed := closedMarker
+	tests := []struct {
+		name          string
+		form          webOpeningHours
+		want          webOpeningHours
+		closedallweek bool
+	}{
+		{"unknown", webOpeningHours{}, webOpeningHours{}, false},
+		{"closed all week", webOpeningHours{Monday: closed, Tuesday: closed, Wednesday: closed, Thursday: closed,
+			Friday: closed, Saturday: closed, Sunday: closed},
+			webOpeningHours{Monday: closed, Tuesday: closed, Wednesday: closed, Thursday: closed, Friday: closed,
+				Saturday: closed, Sunday: closed}, true},
+		{"single closed day", webOpeningHours{Monday: "closed"},
+			webOpeningHours{Monday: closed, Tuesday: closed, Wednesday: closed, Thursday: closed, Friday: closed,
+				Saturday: closed, Sunday: closed}, true},
+		{"full week", webOpeningHours{Monday: "11:30-14:00; 17:00-23:00", Tuesday: "closed", Wednesday: "9-17",
+			Thursday: "17:00-24:00", Friday: "22:00-02:00", Saturday: "22:00-02:00", Sunday: "24h"},
+			webOpeningHours{Monday: "11:30-14:00; 17:00-23:00", Tuesday: closed, Wednesday: "09:00-17:00",
+				Thursday: "17:00-24:00", Friday: "22:00-02:00", Saturday: "22:00-02:00", Sunday: allDayMarker}, false},
+		{"empty days are closed next to others", webOpeningHours{Saturday: "12:00-15:00"},
+			webOpeningHours{Monday: closed, Tuesday: closed, Wednesday: closed, Thursday: closed, Friday: closed,
+				Saturday: "12:00-15:00", Sunday: closed}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := convertWebVenuetoVenue(webVenue{Name: "Roma", OpeningHours: tt.form})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.ClosedAllWeek != tt.closedallweek {
+				t.Errorf("ClosedAllWeek = %v, want %v", v.ClosedAllWeek, tt.closedallweek)
+			}
+			got := convertPeriodstoWebOpeningHours(v.OpeningHours.Periods, v.ClosedAllWeek)
+			if got != tt.want {
+				t.Errorf("round trip of %+v = %+v, want %+v", tt.form, got, tt.want)
+			}
+			//the result is stable, converting it once more gives back the same periods
+			again, err := convertWebVenuetoVenue(webVenue{Name: "Roma", OpeningHours: got})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(again.OpeningHours.Periods, v.OpeningHours.Periods) || again.ClosedAllWeek != v.ClosedAllWeek {
+				t.Errorf("second round trip changed the periods to %+v", again.OpeningHours.Periods)
+			}
+		})
+	}
+}
+
+func TestConvertPeriodsFromPlaces(t *testing.T) {
+	//the Places API writes a day open around the clock as one period without closing time for the whole week
+	got := convertPeriodstoWebOpeningHours([]maps.OpeningHoursPeriod{
+		{Open: maps.OpeningHoursOpenClose{Day: time.Sunday, Time: "0000"}}}, false)
+	want := webOpeningHours{Monday: allDayMarker, Tuesday: allDayMarker, Wednesday: allDayMarker,
+		Thursday: allDayMarker, Friday: allDayMarker, Saturday: allDayMarker, Sunday: allDayMarker}
+	if got != want {
+		t.Errorf("always open = %+v, want %+v", got, want)
+	}
+}
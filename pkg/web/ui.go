@@ -1,19 +1,15 @@
 package web
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"html"
 	"html/template"
-	"io"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/philmacfly/wheretoeat/pkg/service"
 	"github.com/philmacfly/wheretoeat/pkg/venue"
 
 	"github.com/gorilla/mux"
@@ -31,6 +27,12 @@ const successmessage = `<div class="alert alert alert-success" role="alert">
   $MESSAGE$
 </div>`
 
+const warningmessage = `<div class="alert alert-warning" role="alert">
+  <span class="glyphicon glyphicon-exclamation-sign" aria-hidden="true"></span>
+  <span class="sr-only">Warning:</span>
+  $MESSAGE$
+</div>`
+
 var navitems [][]template.HTML
 
 func createNavitem(name string, link string) []template.HTML {
@@ -78,64 +80,51 @@ func buildMessage(tp string, message string) template.HTML {
 	return template.HTML(strings.Replace(tp, "$MESSAGE$", message, -1))
 }
 
-func getNewHTTPRequest(method string, endpoint string, in io.Reader) (*http.Request, error) {
-	var req *http.Request
-	var err error
-	url := "http://127.0.0.1:4334/api/" + endpoint
-	fmt.Println(url)
-	req, err = http.NewRequest(method, url, in)
-	if err != nil {
-		return req, err
-	}
-	return req, nil
+//formPrecondition only lets changes be applied to the revision of the venue the form was built from
+func formPrecondition(r *http.Request) service.Precondition {
+	revision, _ := strconv.Atoi(r.FormValue("revision"))
+	return service.AtRevision(revision)
 }
 
-func sendHTTPRequest(method string, endpoint string, in io.Reader, v interface{}) error {
-	req, err := getNewHTTPRequest(method, endpoint, in)
-	if err != nil {
-		return errors.New("Error creating request: " + err.Error())
+func showtemplate(w http.ResponseWriter, name string, data interface{}) {
+	t, ok := templates[name]
+	if !ok {
+		fmt.Fprintln(w, "Error parsing template: no template", name)
+		return
 	}
-
-	resp, err := http.DefaultClient.Do(req)
+	err := t.Execute(w, data)
 	if err != nil {
-		return errors.New("Error executing request: " + err.Error())
-	}
-
-	defer resp.Body.Close()
-	if resp.StatusCode > 299 {
-		fmt.Println(resp.StatusCode)
-		decoder := json.NewDecoder(resp.Body)
-		var er errorResponse
-		err = decoder.Decode(&er)
-		if err != nil {
-			data, _ := ioutil.ReadAll(resp.Body)
-			fmt.Println(string(data))
-			return errors.New("Error while decoding Error response. Only God can help you now:" + err.Error())
-		}
-		return errors.New("Got negativ status code: " + er.Errormessage)
-	}
-
-	if v != nil {
-		decoder := json.NewDecoder(resp.Body)
-		err = decoder.Decode(v)
-		if err != nil {
-			return errors.New("Unable to decode on given Interface: " + err.Error())
-		}
+		fmt.Fprintln(w, "Error executing template:", err)
+		return
 	}
-	return nil
 }
 
-func showtemplate(w http.ResponseWriter, path string, data interface{}) {
-	t, err := template.ParseFiles(path)
+//showVenueConflict shows the current state of the venue next to the change the user wanted to make,
+//so it can be sent again against the current revision or be discarded
+func showVenueConflict(w http.ResponseWriter, id string, action string, values map[string]string, changes []conflictChange) {
+	var vcp venueConflictPage
+	tp := "venue/conflict.html"
+	vcp.Default.Navbar = buildNavbar(overviewActive)
+	vcp.Default.Pagename = "Venue Changed"
+
+	v, err := service.GetVenue(id)
 	if err != nil {
-		fmt.Fprintln(w, "Error parsing template:", err)
+		vcp.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+		showtemplate(w, tp, vcp)
 		return
 	}
-	err = t.Execute(w, data)
-	if err != nil {
-		fmt.Fprintln(w, "Error executing template:", err)
-		return
+	vcp.Default.Message = buildMessage(warningmessage, "Someone else changed this venue while you were working on it. "+
+		"Check the current values below and decide whether your change should still be applied.")
+	vcp.Venue = convertVenuetoWebVenue(v)
+	vcp.Changes = changes
+	vcp.Action = action
+	vcp.Values = values
+	if vcp.Values == nil {
+		vcp.Values = make(map[string]string)
 	}
+	vcp.Values["id"] = v.VenueID
+	vcp.Values["revision"] = strconv.Itoa(v.Revision)
+	showtemplate(w, tp, vcp)
 }
 
 func mainUIHandler(w http.ResponseWriter, r *http.Request) {
@@ -144,93 +133,195 @@ func mainUIHandler(w http.ResponseWriter, r *http.Request) {
 
 func venueUIListHandler(w http.ResponseWriter, r *http.Request) {
 	var mp mainPage
-	tp := "../../web/templates/main.html"
+	tp := "main.html"
 	mp.Default.Navbar = buildNavbar(overviewActive)
 	mp.Default.Pagename = "Venue List"
 
-	var vv []venue.Venue
-
-	err := sendHTTPRequest("GET", "venue/list", nil, &vv)
+	mp.ShowArchived = r.FormValue("archived") != ""
+	vv, err := service.ListVenues("")
 	if err != nil {
-		mp.Default.Message = buildMessage(errormessage, "Error creating venue/list request: "+err.Error())
+		mp.Default.Message = buildMessage(errormessage, "Error listing venues: "+err.Error())
 		showtemplate(w, tp, mp)
 		return
 	}
 	for _, v := range vv {
+		if v.Archived && !mp.ShowArchived {
+			continue
+		}
 		mp.Venues = append(mp.Venues, convertVenuetoWebVenue(v))
 	}
+
+	problems, err := service.Problems()
+	if err != nil {
+		mp.Default.Message = buildMessage(warningmessage, "Error getting health of the venue storage: "+err.Error())
+	} else if len(problems) > 0 {
+		var files []string
+		for _, p := range problems {
+			files = append(files, p.File)
+		}
+		mp.Default.Message = buildMessage(warningmessage, strconv.Itoa(len(files))+" venue files could not be loaded and were moved to quarantine: "+
+			strings.Join(files, ", ")+". See /api/admin/health for details.")
+	}
 	showtemplate(w, tp, mp)
 }
 
 func venueUIViewHandler(w http.ResponseWriter, r *http.Request) {
+	showVenue(w, r.FormValue("id"), "")
+}
+
+//showVenue shows the view page of the venue with the message on top
+func showVenue(w http.ResponseWriter, id string, message template.HTML) {
 	var vvp venueViewPage
-	tp := "../../web/templates/venue/view.html"
+	tp := "venue/view.html"
 	vvp.Default.Navbar = buildNavbar(overviewActive)
 	vvp.Default.Pagename = "Venue View"
+	vvp.Default.Message = message
 
-	id := r.FormValue("id")
-
-	v := venue.Venue{}
-
-	err := sendHTTPRequest("GET", "venue/"+id, nil, &v)
+	v, err := service.GetVenue(id)
 	if err != nil {
-		vvp.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+		vvp.Default.Message = buildMessage(errormessage, "Error getting venue: "+err.Error())
 		showtemplate(w, tp, vvp)
 		return
 	}
 	vvp.Venue = convertVenuetoWebVenue(v)
+	history, err := service.VenueHistory(v.VenueID)
+	if err != nil {
+		vvp.Default.Message = buildMessage(errormessage, "Error getting history: "+err.Error())
+	}
+	vvp.History = convertHistorytoTimeline(history)
+	if v.ClosedOnHolidays {
+		for _, h := range service.UpcomingHolidays(5) {
+			vvp.Holidays = append(vvp.Holidays, h.Date.Format(layoutISO)+" "+h.Name)
+		}
+	}
 	showtemplate(w, tp, vvp)
 }
 
+func venueUIAddExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	values := map[string]string{"from": r.FormValue("from"), "to": r.FormValue("to"), "kind": r.FormValue("kind"),
+		"hours": r.FormValue("hours"), "reason": r.FormValue("reason")}
+	e, err := convertFormtoException(values["from"], values["to"], values["kind"], values["hours"], values["reason"])
+	if err != nil {
+		showVenue(w, id, buildMessage(errormessage, "Error converting exception: "+err.Error()))
+		return
+	}
+	_, err = service.AddException(id, formPrecondition(r), e)
+	if service.KindOf(err) == service.Conflict {
+		we := convertExceptiontoWebException(0, e)
+		changes := []conflictChange{{Field: "Exceptions", Yours: "add " + we.From + " to " + we.To + ": " + we.Hours}}
+		showVenueConflict(w, id, "add-exception", values, changes)
+		return
+	}
+	if err != nil {
+		showVenue(w, id, buildMessage(errormessage, "Error saving exception: "+err.Error()))
+		return
+	}
+	http.Redirect(w, r, "?action=view&id="+id, http.StatusSeeOther)
+}
+
+func venueUIDeleteExceptionHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	index, _ := strconv.Atoi(r.FormValue("index"))
+	_, err := service.DeleteException(id, formPrecondition(r), index)
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Exceptions", Yours: "delete exception " + strconv.Itoa(index+1)}}
+		showVenueConflict(w, id, "delete-exception", map[string]string{"index": r.FormValue("index")}, changes)
+		return
+	}
+	if err != nil {
+		showVenue(w, id, buildMessage(errormessage, "Error deleting exception: "+err.Error()))
+		return
+	}
+	http.Redirect(w, r, "?action=view&id="+id, http.StatusSeeOther)
+}
+
 func venueUIAddHandler(w http.ResponseWriter, r *http.Request) {
 	var vap venueAddPage
-	tp := "../../web/templates/venue/add.html"
+	tp := "venue/add.html"
 	vap.Default.Navbar = buildNavbar(addvenueActive)
 	vap.Default.Pagename = "Add Venue"
 
 	name := r.FormValue("Name")
 	address := r.FormValue("Address")
-	v := venue.Venue{}
+	vap.Venue = convertVenuetoWebVenue(venue.Venue{Name: name, Address: address})
 	if name != "" {
 		query := name
 		if address != "" {
 			query = query + ", " + address
 		}
-		err := sendHTTPRequest("GET", "venue/getfromplaces/"+query, nil, &v)
+		cc, err := service.SearchPlaces(query)
 		if err != nil {
-			vap.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+			vap.Default.Message = buildMessage(errormessage, "Error searching venue: "+err.Error())
 			showtemplate(w, tp, vap)
 			return
 		}
+		if len(cc) < 1 {
+			vap.Default.Message = buildMessage(warningmessage, "Nothing found for "+query)
+		}
+		vap.Candidates = cc
 	}
 
-	vap.Venue = convertVenuetoWebVenue(v)
 	showtemplate(w, tp, vap)
 }
 
-func venueUISaveHandler(w http.ResponseWriter, r *http.Request) {
+func venueUIChoosePlaceHandler(w http.ResponseWriter, r *http.Request) {
 	var vap venueAddPage
-	tp := "../../web/templates/venue/add.html"
+	tp := "venue/add.html"
 	vap.Default.Navbar = buildNavbar(addvenueActive)
 	vap.Default.Pagename = "Add Venue"
 
+	rating, _ := strconv.ParseFloat(r.FormValue("rating"), 64)
+	c := venue.PlaceCandidate{
+		PlaceID: r.FormValue("placeid"),
+		Name:    r.FormValue("name"),
+		Address: r.FormValue("address"),
+		Rating:  rating,
+	}
+	v, err := service.VenueFromPlace(c)
+	if err != nil {
+		vap.Default.Message = buildMessage(errormessage, "Error getting details of the place: "+err.Error())
+	}
+	vap.Venue = convertVenuetoWebVenue(v)
+	showtemplate(w, tp, vap)
+}
+
+func readVenueForm(r *http.Request) webVenue {
 	var wv webVenue
+	wv.VenueID = r.FormValue("id")
+	wv.Revision, _ = strconv.Atoi(r.FormValue("revision"))
 	wv.Name = r.FormValue("Name")
 	wv.Address = r.FormValue("Address")
 	rating := r.FormValue("Rating")
 	ra, _ := strconv.Atoi(rating)
 	wv.Rating = ra
-	wv.GooglePlaceID = r.FormValue("placesid")
+	wv.PlaceProvider = r.FormValue("placeprovider")
+	wv.PlaceID = r.FormValue("placesid")
+	//IDs entered by hand belong to the configured provider
+	if wv.PlaceID != "" && wv.PlaceProvider == "" {
+		wv.PlaceProvider = venue.PlaceProviderName()
+	}
 	wv.Website = r.FormValue("Website")
 	wv.PhoneNumber = r.FormValue("phone")
 	wv.Notes = r.FormValue("Notes")
+	wv.ClosedOnHolidays = r.FormValue("closedonholidays") != ""
 	wv.OpeningHours.Monday = r.FormValue("Monday")
 	wv.OpeningHours.Tuesday = r.FormValue("Tuesday")
 	wv.OpeningHours.Wednesday = r.FormValue("Wednesday")
 	wv.OpeningHours.Thursday = r.FormValue("Thursday")
 	wv.OpeningHours.Friday = r.FormValue("Friday")
-	wv.OpeningHours.Saturday = r.FormValue("Monday")
+	wv.OpeningHours.Saturday = r.FormValue("Saturday")
 	wv.OpeningHours.Sunday = r.FormValue("Sunday")
+	return wv
+}
+
+func venueUISaveHandler(w http.ResponseWriter, r *http.Request) {
+	var vap venueAddPage
+	tp := "venue/add.html"
+	vap.Default.Navbar = buildNavbar(addvenueActive)
+	vap.Default.Pagename = "Add Venue"
+
+	wv := readVenueForm(r)
 
 	v, err := convertWebVenuetoVenue(wv)
 	if err != nil {
@@ -240,13 +331,9 @@ func venueUISaveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b := new(bytes.Buffer)
-	encoder := json.NewEncoder(b)
-	encoder.Encode(v)
-
-	err = sendHTTPRequest("POST", "venue", b, &v)
+	_, err = service.CreateVenue(v)
 	if err != nil {
-		vap.Default.Message = buildMessage(errormessage, "Error sending Venue request: "+err.Error())
+		vap.Default.Message = buildMessage(errormessage, "Error saving Venue: "+err.Error())
 		vap.Venue = wv
 		showtemplate(w, tp, vap)
 		return
@@ -257,17 +344,83 @@ func venueUISaveHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func venueUIEditHandler(w http.ResponseWriter, r *http.Request) {
+	var vep venueEditPage
+	tp := "venue/edit.html"
+	vep.Default.Navbar = buildNavbar(overviewActive)
+	vep.Default.Pagename = "Edit Venue"
+
+	id := r.FormValue("id")
+
+	v, err := service.GetVenue(id)
+	if err != nil {
+		vep.Default.Message = buildMessage(errormessage, "Error getting venue: "+err.Error())
+		showtemplate(w, tp, vep)
+		return
+	}
+	vep.Venue = convertVenuetoWebVenue(v)
+	showtemplate(w, tp, vep)
+}
+
+func venueUIEditSaveHandler(w http.ResponseWriter, r *http.Request) {
+	var vep venueEditPage
+	tp := "venue/edit.html"
+	vep.Default.Navbar = buildNavbar(overviewActive)
+	vep.Default.Pagename = "Edit Venue"
+
+	wv := readVenueForm(r)
+	vep.Venue = wv
+
+	v, err := convertWebVenuetoVenue(wv)
+	if err != nil {
+		vep.Default.Message = buildMessage(errormessage, "Error converting venue: "+err.Error())
+		showtemplate(w, tp, vep)
+		return
+	}
+
+	//only the fields of the form are changed, so visits and everything else stay untouched
+	_, err = service.UpdateVenue(wv.VenueID, formPrecondition(r), func(stored *venue.Venue) error {
+		stored.Name = v.Name
+		stored.Address = v.Address
+		stored.Rating = v.Rating
+		stored.PlaceProvider = v.PlaceProvider
+		stored.PlaceID = v.PlaceID
+		stored.Website = v.Website
+		stored.PhoneNumber = v.PhoneNumber
+		stored.Notes = v.Notes
+		stored.SetOpeningHours(v.OpeningHours.Periods, v.ClosedAllWeek)
+		stored.ClosedOnHolidays = v.ClosedOnHolidays
+		return nil
+	})
+	if service.KindOf(err) == service.Conflict {
+		current, err := service.GetVenue(wv.VenueID)
+		if err != nil {
+			vep.Default.Message = buildMessage(errormessage, "Error getting venue: "+err.Error())
+			showtemplate(w, tp, vep)
+			return
+		}
+		//both sides go through the conversion, so differently formatted opening hours don't show up as change
+		changes := venueChanges(convertVenuetoWebVenue(v), convertVenuetoWebVenue(current))
+		showVenueConflict(w, wv.VenueID, "edit-save", wv.formValues(), changes)
+		return
+	}
+	if err != nil {
+		vep.Default.Message = buildMessage(errormessage, "Error saving Venue: "+err.Error())
+		showtemplate(w, tp, vep)
+		return
+	}
+	http.Redirect(w, r, "?action=view&id="+wv.VenueID, http.StatusSeeOther)
+}
+
 func venueUINotVisitedHandler(w http.ResponseWriter, r *http.Request) {
 	var mp mainPage
-	tp := "../../web/templates/main.html"
+	tp := "main.html"
 	mp.Default.Navbar = buildNavbar(overviewActive)
 	mp.Default.Pagename = "Venue List"
 
-	v := venue.Venue{}
-
-	err := sendHTTPRequest("GET", "venue/notvisited", nil, &v)
+	v, err := service.PickNotVisited(r.FormValue("includeclosed") != "")
 	if err != nil {
-		mp.Default.Message = buildMessage(errormessage, "Error getting not visited venue request: "+err.Error())
+		mp.Default.Message = buildMessage(errormessage, "Error getting not visited venue: "+err.Error())
 		showtemplate(w, tp, mp)
 		return
 	}
@@ -275,119 +428,245 @@ func venueUINotVisitedHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func readVisitForm(r *http.Request) webVisit {
+	var wv webVisit
+	wv.VisitID = r.FormValue("visitid")
+	wv.Date = r.FormValue("date")
+	wv.Attendees = r.FormValue("attendees")
+	ra, _ := strconv.Atoi(r.FormValue("rating"))
+	wv.Rating = ra
+	wv.Dishes = r.FormValue("dishes")
+	wv.Cost = r.FormValue("cost")
+	wv.Currency = r.FormValue("currency")
+	wv.Comment = r.FormValue("comment")
+	return wv
+}
+
 func venueUIAddVisitHandler(w http.ResponseWriter, r *http.Request) {
 	var vap venueAddVisitPage
-	tp := "../../web/templates/venue/add-visit.html"
+	tp := "venue/add-visit.html"
 	vap.Default.Navbar = buildNavbar(overviewActive)
 	vap.Default.Pagename = "Venue Add Visit"
+	vap.Action = "add-visit-execute"
 
 	id := r.FormValue("id")
 
-	v := venue.Venue{}
-
-	err := sendHTTPRequest("GET", "venue/"+id, nil, &v)
+	v, err := service.GetVenue(id)
 	if err != nil {
-		vap.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+		vap.Default.Message = buildMessage(errormessage, "Error getting venue: "+err.Error())
 		showtemplate(w, tp, vap)
 		return
 	}
 	vap.Venue = convertVenuetoWebVenue(v)
 
-	vap.Venue.LastVisit = time.Now().Format(layoutISO)
+	vap.Visit.Date = time.Now().Format(layoutISO)
 
 	showtemplate(w, tp, vap)
 }
 
 func venueUIAddVisitExecuteHandler(w http.ResponseWriter, r *http.Request) {
 	var vap venueAddVisitPage
-	tp := "../../web/templates/venue/add-visit.html"
+	tp := "venue/add-visit.html"
 	vap.Default.Navbar = buildNavbar(overviewActive)
 	vap.Default.Pagename = "Venue Add Visit"
+	vap.Action = "add-visit-execute"
 
 	id := r.FormValue("id")
-	date := r.FormValue("date")
+	revision := r.FormValue("revision")
+	vap.Venue = webVenue{VenueID: id}
+	vap.Venue.Revision, _ = strconv.Atoi(revision)
+	vap.Visit = readVisitForm(r)
 
-	d, err := time.Parse(layoutISO, date)
+	visit, err := convertWebVisittoVisit(vap.Visit)
 	if err != nil {
-		vap.Default.Message = buildMessage(errormessage, "Error parsing given date: "+err.Error())
+		vap.Default.Message = buildMessage(errormessage, err.Error())
 		showtemplate(w, tp, vap)
 		return
 	}
 
-	var req addVisitsRequest
+	_, err = service.AddVisits(id, formPrecondition(r), []venue.Visit{visit})
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Visits", Yours: "Add visit on " + vap.Visit.Date}}
+		showVenueConflict(w, id, "add-visit-execute", vap.Visit.formValues(), changes)
+		return
+	}
+	if err != nil {
+		vap.Default.Message = buildMessage(errormessage, "Error saving visit: "+err.Error())
+		showtemplate(w, tp, vap)
+		return
+	}
+	http.Redirect(w, r, "?action=view&id="+id, http.StatusTemporaryRedirect)
+}
 
-	req.Visits = append(req.Visits, d)
+func venueUIEditVisitHandler(w http.ResponseWriter, r *http.Request) {
+	var vap venueAddVisitPage
+	tp := "venue/add-visit.html"
+	vap.Default.Navbar = buildNavbar(overviewActive)
+	vap.Default.Pagename = "Venue Edit Visit"
+	vap.Action = "edit-visit-execute"
 
-	b := new(bytes.Buffer)
-	encoder := json.NewEncoder(b)
-	encoder.Encode(req)
+	id := r.FormValue("id")
+	visitid := r.FormValue("visitid")
 
-	err = sendHTTPRequest("POST", "venue/"+id+"/addvisits", b, nil)
+	visit, v, err := service.GetVisit(id, visitid)
 	if err != nil {
-		vap.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+		vap.Default.Message = buildMessage(errormessage, "Error getting visit: "+err.Error())
+		showtemplate(w, tp, vap)
+		return
+	}
+	vap.Venue = convertVenuetoWebVenue(v)
+	vap.Visit = convertVisittoWebVisit(visit)
+
+	showtemplate(w, tp, vap)
+}
+
+func venueUIEditVisitExecuteHandler(w http.ResponseWriter, r *http.Request) {
+	var vap venueAddVisitPage
+	tp := "venue/add-visit.html"
+	vap.Default.Navbar = buildNavbar(overviewActive)
+	vap.Default.Pagename = "Venue Edit Visit"
+	vap.Action = "edit-visit-execute"
+
+	id := r.FormValue("id")
+	revision := r.FormValue("revision")
+	vap.Venue = webVenue{VenueID: id}
+	vap.Venue.Revision, _ = strconv.Atoi(revision)
+	vap.Visit = readVisitForm(r)
+
+	visit, err := convertWebVisittoVisit(vap.Visit)
+	if err != nil {
+		vap.Default.Message = buildMessage(errormessage, err.Error())
+		showtemplate(w, tp, vap)
+		return
+	}
+
+	_, _, err = service.UpdateVisit(id, visit.VisitID, formPrecondition(r), func(stored *venue.Visit) error {
+		*stored = visit
+		return nil
+	})
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Visit of " + vap.Visit.Date, Yours: "Change visit"}}
+		showVenueConflict(w, id, "edit-visit-execute", vap.Visit.formValues(), changes)
+		return
+	}
+	if err != nil {
+		vap.Default.Message = buildMessage(errormessage, "Error saving visit: "+err.Error())
 		showtemplate(w, tp, vap)
 		return
 	}
 	http.Redirect(w, r, "?action=view&id="+id, http.StatusTemporaryRedirect)
 }
 
+func venueUIDeleteVisitHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	visitid := r.FormValue("visitid")
+	_, err := service.DeleteVisit(id, visitid, formPrecondition(r))
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Visits", Yours: "Delete visit"}}
+		showVenueConflict(w, id, "delete-visit", map[string]string{"visitid": visitid}, changes)
+		return
+	}
+	if err != nil {
+		showVenue(w, id, buildMessage(errormessage, "Error deleting visit: "+err.Error()))
+		return
+	}
+	http.Redirect(w, r, "?action=view&id="+id, http.StatusSeeOther)
+}
+
 func venueUIUpdateVenuesfromPlacesHandler(w http.ResponseWriter, r *http.Request) {
 	var udp updateDonePage
-	tp := "../../web/templates/venue/update-done.html"
+	tp := "venue/update-done.html"
 	udp.Default.Navbar = buildNavbar(overviewActive)
 	udp.Default.Pagename = "Update Done"
 
-	err := sendHTTPRequest("POST", "venue/updatefromplaces", nil, nil)
+	report, err := service.UpdateFromPlaces()
 	if err != nil {
-		udp.Default.Message = buildMessage(errormessage, "Error getting venue request: "+err.Error())
+		udp.Default.Message = buildMessage(errormessage, "Error updating venues: "+err.Error())
 		showtemplate(w, tp, udp)
 		return
 	}
-	udp.Default.Message = buildMessage(successmessage, "All Venues updated")
+	udp.Report = report
+	if report.Failed > 0 {
+		udp.Default.Message = buildMessage(warningmessage, "Not all Venues could be updated: "+report.Summary())
+	} else {
+		udp.Default.Message = buildMessage(successmessage, "All Venues updated: "+report.Summary())
+	}
 	showtemplate(w, tp, udp)
 }
 
 func venueUIDeleteHandler(w http.ResponseWriter, r *http.Request) {
 	var mp mainPage
-	tp := "../../web/templates/main.html"
+	tp := "main.html"
 	mp.Default.Navbar = buildNavbar(overviewActive)
 	mp.Default.Pagename = "Venue List"
 
 	id := r.FormValue("id")
-	err := sendHTTPRequest("DELETE", "venue/"+id, nil, nil)
+	err := service.DeleteVenue(id, formPrecondition(r))
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Venue", Yours: "Delete venue"}}
+		showVenueConflict(w, id, "delete", nil, changes)
+		return
+	}
 	if err != nil {
-		mp.Default.Message = buildMessage(errormessage, "Error getting not visited venue request: "+err.Error())
+		mp.Default.Message = buildMessage(errormessage, "Error deleting venue: "+err.Error())
 		showtemplate(w, tp, mp)
 		return
 	}
 	http.Redirect(w, r, "?action=list", http.StatusTemporaryRedirect)
 }
 
+func venueUIArchiveHandler(w http.ResponseWriter, r *http.Request) {
+	var mp mainPage
+	tp := "main.html"
+	mp.Default.Navbar = buildNavbar(overviewActive)
+	mp.Default.Pagename = "Venue List"
+
+	id := r.FormValue("id")
+	action := r.FormValue("action")
+	archived := action == "archive"
+	_, err := service.ArchiveVenue(id, formPrecondition(r), archived)
+	if service.KindOf(err) == service.Conflict {
+		changes := []conflictChange{{Field: "Archived", Yours: strconv.FormatBool(archived)}}
+		showVenueConflict(w, id, action, nil, changes)
+		return
+	}
+	if err != nil {
+		mp.Default.Message = buildMessage(errormessage, "Error archiving venue: "+err.Error())
+		showtemplate(w, tp, mp)
+		return
+	}
+	http.Redirect(w, r, "?action=list", http.StatusSeeOther)
+}
+
 func venueUINextOptionHandler(w http.ResponseWriter, r *http.Request) {
-	var nop nextOptionsPage
-	tp := "../../web/templates/venue/next.html"
-	nop.Default.Navbar = buildNavbar(nextVisitedActive)
-	nop.Default.Pagename = "Select next options"
+	tp := "venue/next.html"
+	nop := newNextOptionsPage("")
 	showtemplate(w, tp, nop)
 }
 
 func venueUINextHandler(w http.ResponseWriter, r *http.Request) {
-	var nop nextOptionsPage
-	tp := "../../web/templates/venue/next.html"
-	nop.Default.Navbar = buildNavbar(nextVisitedActive)
-	nop.Default.Pagename = "Select next options"
-
-	old := r.FormValue("old")
-	new := r.FormValue("new")
-	weighted := r.FormValue("weighted")
-
-	var v venue.Venue
-
-	options := "?old=" + old + "&new=" + new + "&weighted=" + weighted
+	tp := "venue/next.html"
+	nop := newNextOptionsPage(r.FormValue("strategy"))
+
+	o := service.PickOptions{
+		Old:           r.FormValue("old") != "",
+		New:           r.FormValue("new") != "",
+		Strategy:      r.FormValue("strategy"),
+		IncludeClosed: r.FormValue("includeclosed") != "",
+	}
+	if r.FormValue("onlyopen") != "" {
+		at, err := service.ParseAt(r.FormValue("at"))
+		if err != nil {
+			nop.Default.Message = buildMessage(errormessage, "Error getting next venue: "+err.Error())
+			showtemplate(w, tp, nop)
+			return
+		}
+		o.At = at
+	}
 
-	err := sendHTTPRequest("GET", "venue/next"+options, nil, &v)
+	v, err := service.PickNext(o)
 	if err != nil {
-		nop.Default.Message = buildMessage(errormessage, "Error getting next venue request: "+err.Error())
+		nop.Default.Message = buildMessage(errormessage, "Error getting next venue: "+err.Error())
 		showtemplate(w, tp, nop)
 		return
 	}
@@ -401,18 +680,36 @@ func venueUIHandler(w http.ResponseWriter, r *http.Request) {
 		venueUIViewHandler(w, r)
 	case "add":
 		venueUIAddHandler(w, r)
+	case "choose-place":
+		venueUIChoosePlaceHandler(w, r)
 	case "save":
 		venueUISaveHandler(w, r)
+	case "edit":
+		venueUIEditHandler(w, r)
+	case "edit-save":
+		venueUIEditSaveHandler(w, r)
 	case "not-visited":
 		venueUINotVisitedHandler(w, r)
 	case "add-visit":
 		venueUIAddVisitHandler(w, r)
+	case "add-exception":
+		venueUIAddExceptionHandler(w, r)
+	case "delete-exception":
+		venueUIDeleteExceptionHandler(w, r)
 	case "add-visit-execute":
 		venueUIAddVisitExecuteHandler(w, r)
+	case "edit-visit":
+		venueUIEditVisitHandler(w, r)
+	case "edit-visit-execute":
+		venueUIEditVisitExecuteHandler(w, r)
+	case "delete-visit":
+		venueUIDeleteVisitHandler(w, r)
 	case "update-from-places":
 		venueUIUpdateVenuesfromPlacesHandler(w, r)
 	case "delete":
 		venueUIDeleteHandler(w, r)
+	case "archive", "unarchive":
+		venueUIArchiveHandler(w, r)
 	case "next":
 		venueUINextOptionHandler(w, r)
 	case "get-next-venue":
@@ -424,7 +721,7 @@ func venueUIHandler(w http.ResponseWriter, r *http.Request) {
 
 func getUIRouter(prefix string) *mux.Router {
 	r := mux.NewRouter().PathPrefix(prefix).Subrouter()
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/ui/static/", http.FileServer(http.Dir("../../web/static/"))))
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/ui/static/", http.FileServer(http.FS(staticFiles()))))
 	r.HandleFunc("/", mainUIHandler)
 	r.HandleFunc("/venue/", venueUIHandler)
 	return r
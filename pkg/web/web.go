@@ -3,9 +3,9 @@ package web
 import (
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/philmacfly/wheretoeat/pkg/venue"
 )
 
 type errorResponse struct {
@@ -13,8 +13,13 @@ type errorResponse struct {
 	Errormessage string `json:"errormessage"`
 }
 
+type healthResponse struct {
+	Status   string               `json:"status"`
+	Problems []venue.StoreProblem `json:"problems"`
+}
+
 type addVisitsRequest struct {
-	Visits []time.Time `json:"visits"`
+	Visits []venue.Visit `json:"visits"`
 }
 
 func mainHandler(w http.ResponseWriter, r *http.Request) {
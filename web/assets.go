@@ -0,0 +1,8 @@
+//Package web holds the templates and static files of the frontend, so they are compiled into the binary
+package web
+
+import "embed"
+
+//Files holds the templates folder with the HTML templates and the static folder with Bootstrap, jQuery and open-iconic
+//go:embed templates static
+var Files embed.FS